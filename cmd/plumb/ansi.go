@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// span styles the bytes of a line from start up to the next span.
+type span struct {
+	start int
+	style
+}
+
+// maxEscape bounds the length of an escape sequence, longer ones are dropped.
+const maxEscape = 64
+
+// escape adds b to the pending escape sequence and handles the sequence once
+// it is complete. It reports whether b was consumed.
+func (l *lineReader) escape(b byte) bool {
+	if len(l.esc) == 0 {
+		if b != 0x1b {
+			return false
+		}
+		l.esc = append(l.esc, b)
+		return true
+	}
+	l.esc = append(l.esc, b)
+	if len(l.esc) > maxEscape {
+		l.esc = l.esc[:0]
+		return true
+	}
+	if len(l.esc) == 2 {
+		if b == '[' || b == ']' {
+			return true
+		}
+		l.esc = l.esc[:0] // two byte sequence
+		return true
+	}
+	switch l.esc[1] {
+	case '[':
+		if b < 0x40 || b > 0x7e {
+			return true
+		}
+		if b == 'm' {
+			l.sgr(string(l.esc[2 : len(l.esc)-1]))
+		}
+	case ']':
+		// operating system command, ends with BEL or ST
+		if b != 0x07 && !(b == '\\' && l.esc[len(l.esc)-2] == 0x1b) {
+			return true
+		}
+	}
+	l.esc = l.esc[:0]
+	return true
+}
+
+// sgr applies the parameters of a select graphic rendition sequence to the
+// current style and starts a new span with it.
+func (l *lineReader) sgr(params string) {
+	if params == "" {
+		params = "0"
+	}
+	ps := strings.Split(params, ";")
+	for i := 0; i < len(ps); i++ {
+		p, _ := strconv.Atoi(ps[i])
+		switch {
+		case p == 0:
+			l.cur = style{}
+		case p == 1:
+			l.cur.fg |= attrBold
+		case p == 4:
+			l.cur.fg |= attrUnderline
+		case p == 7:
+			l.cur.fg |= attrReverse
+		case p == 22:
+			l.cur.fg &^= attrBold
+		case p == 24:
+			l.cur.fg &^= attrUnderline
+		case p == 27:
+			l.cur.fg &^= attrReverse
+		case p >= 30 && p <= 37:
+			l.cur.fg = l.cur.fg&attrs | colorBlack + attr(p-30)
+		case p >= 90 && p <= 97:
+			l.cur.fg = l.cur.fg&attrs | paletteColor(p-90+8)
+		case p == 39:
+			l.cur.fg &= attrs
+		case p >= 40 && p <= 47:
+			l.cur.bg = colorBlack + attr(p-40)
+		case p >= 100 && p <= 107:
+			l.cur.bg = paletteColor(p - 100 + 8)
+		case p == 49:
+			l.cur.bg = colorDefault
+		case p == 38 || p == 48:
+			// extended colors, from the 256 color palette or 24 bit
+			c := colorDefault
+			if i+2 < len(ps) && ps[i+1] == "5" {
+				n, _ := strconv.Atoi(ps[i+2])
+				c = paletteColor(n & 0xff)
+				i += 2
+			} else if i+4 < len(ps) && ps[i+1] == "2" {
+				r, _ := strconv.Atoi(ps[i+2])
+				g, _ := strconv.Atoi(ps[i+3])
+				b, _ := strconv.Atoi(ps[i+4])
+				c = rgbColor(r, g, b)
+				i += 4
+			}
+			if p == 38 {
+				l.cur.fg = l.cur.fg&attrs | c
+			} else {
+				l.cur.bg = c
+			}
+		}
+	}
+	l.mark()
+}
+
+// mark starts a span with the current style at the end of the last line.
+func (l *lineReader) mark() {
+	if l.nocolor {
+		return
+	}
+	n := l.lines.len() - 1
+	spans := l.spans[n]
+	sp := span{len(l.lines.line(n)), l.cur}
+	if len(spans) > 0 && spans[len(spans)-1].start == sp.start {
+		spans = spans[:len(spans)-1]
+	}
+	if len(spans) == 0 && sp.style == (style{}) {
+		return
+	}
+	l.spans[n] = append(spans, sp)
+}
+
+// Spans returns the styles of line i, nil for unstyled lines.
+func (l *lineReader) Spans(i int) []span {
+	l.RLock()
+	defer l.RUnlock()
+	return l.spans[i]
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// glyph returns how r is shown when it can't be drawn as itself: control
+// characters in caret notation and C1 controls as hex escapes. It returns ""
+// for other runes, tabs included.
+func glyph(r rune) string {
+	switch {
+	case r < 0x20 && r != '\t':
+		return "^" + string(r+'@')
+	case r == 0x7f:
+		return "^?"
+	case r >= 0x80 && r < 0xa0:
+		return fmt.Sprintf("\\x%02x", r)
+	}
+	return ""
+}
+
+// binary reports whether b, the start of an input, looks like binary data
+// rather than text: it has NUL bytes or more than a third of it is control
+// characters or not UTF-8.
+func binary(b []byte) bool {
+	if bytes.IndexByte(b, 0) >= 0 {
+		return true
+	}
+	odd, total := 0, 0
+	for len(b) > 0 {
+		r, n := utf8.DecodeRune(b)
+		if r == utf8.RuneError && n == 1 || glyph(r) != "" && !strings.ContainsRune("\n\r\f\x1b", r) {
+			odd++
+		}
+		total++
+		b = b[n:]
+	}
+	return odd*3 > total
+}
+
+// binaryGuard asks before the input is shown when its first read looks like
+// binary data.
+type binaryGuard struct {
+	io.Reader
+	t       *terminal
+	checked bool
+}
+
+func (g *binaryGuard) Read(p []byte) (int, error) {
+	n, err := g.Reader.Read(p)
+	if !g.checked && n > 0 {
+		g.checked = true
+		if binary(p[:n]) {
+			g.t.confirmBinary()
+		}
+	}
+	return n, err
+}
+
+// confirmBinary asks the main loop whether binary input is to be shown and
+// waits for the answer, plumb exits if it is no.
+func (t *terminal) confirmBinary() {
+	ok := make(chan struct{})
+	t.redraws.Lock()
+	t.redraws.binary = ok
+	t.redraws.Unlock()
+	t.requestDraw()
+	<-ok
+}
+
+// askBinary opens the prompt asking whether to show binary input, closing
+// ok if so.
+func (t *terminal) askBinary(ok chan struct{}) {
+	t.prompt = &prompt{
+		label: "the input looks binary, show it anyway? (y/n) ",
+		done: func(s string) error {
+			if s == "y" || s == "yes" {
+				close(ok)
+				return nil
+			}
+			return errExit
+		},
+		cancel: func() { t.quit = true },
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// buffer is an input with where it is looked at. Several can be open, such
+// as the output of go vet and of go test, of which one is shown.
+type buffer struct {
+	name    string
+	stdin   *lineReader
+	child   *child // command whose output is the input, if any
+	view    *view  // lines of stdin shown on the screen
+	selline int    // current line
+	topline int
+	sel     *selection // tokens on the selected line
+	left    int        // first column shown, for long lines
+	visual  bool       // lines from anchor to selline are selected
+	anchor  int
+	links   links         // plumbable tokens of the lines shown
+	marks   map[rune]int  // input lines by mark, see startMark
+	filters []*lineFilter // narrowing the view, see narrow
+	follow  bool          // keep the last line selected as input arrives
+	away    bool          // the user moved away from the last row, see newRows
+	seen    int           // rows when they did
+	lexer   chroma.Lexer  // language of the input, see inputLexer
+	sniffed bool          // the lexer was looked for, nil if none was found
+}
+
+// newBuffer returns an empty buffer read as the first one is.
+func (t *terminal) newBuffer(name string) *buffer {
+	first := t.buffers[0].stdin
+	in := &lineReader{nocolor: first.nocolor, stripCR: first.stripCR, onLine: first.onLine}
+	in.lines.maxLines, in.lines.maxBytes = first.lines.maxLines, first.lines.maxBytes
+	b := &buffer{name: name, stdin: in, view: &view{src: in}}
+	if t.buffers[0].links.enabled() {
+		b.links.lines = map[int]*lineLinks{}
+	}
+	return b
+}
+
+// openBuffer runs command in a new buffer and shows it.
+func (t *terminal) openBuffer(command string) error {
+	b := t.newBuffer(command)
+	if err := t.startChild(b, []string{"sh", "-c", command}); err != nil {
+		return err
+	}
+	t.buffers = append(t.buffers, b)
+	t.showBuffer(len(t.buffers) - 1)
+	return nil
+}
+
+// closeBuffer kills the command of the buffer shown and closes it, the first
+// one stays open.
+func (t *terminal) closeBuffer() error {
+	i := t.bufferIndex()
+	if i == 0 {
+		return fmt.Errorf("the first buffer stays open")
+	}
+	if c := t.child; c != nil {
+		c.kill()
+	}
+	t.stdin.gate.resume()
+	t.buffers = append(t.buffers[:i], t.buffers[i+1:]...)
+	t.showBuffer(i - 1)
+	return nil
+}
+
+// bufferIndex returns the index of the buffer shown.
+func (t *terminal) bufferIndex() int {
+	for i, b := range t.buffers {
+		if b == t.buffer {
+			return i
+		}
+	}
+	return 0
+}
+
+// showBuffer shows buffer i.
+func (t *terminal) showBuffer(i int) {
+	t.buffer = t.buffers[i]
+	t.layout()
+}
+
+// cycleBuffer shows the next buffer in direction dir, wrapping around at the
+// ends.
+func (t *terminal) cycleBuffer(dir int) {
+	if len(t.buffers) == 1 {
+		t.message = "no other buffers, open one with :new cmd"
+		return
+	}
+	n := len(t.buffers)
+	t.showBuffer((t.bufferIndex() + dir + n) % n)
+}
+
+// bufferLabel describes buffer b for the buffer list.
+func bufferLabel(b *buffer) string {
+	lines, _, ended, err := b.stdin.state()
+	state := "running"
+	switch {
+	case err != nil:
+		state = err.Error()
+	case ended:
+		state = "ended"
+	}
+	return fmt.Sprintf("%-30s %6d lines  %s", b.name, lines, state)
+}
+
+// showBuffers opens the menu of the buffers, to show one.
+func (t *terminal) showBuffers() {
+	items := make([]string, len(t.buffers))
+	for i, b := range t.buffers {
+		items[i] = bufferLabel(b)
+	}
+	t.menu = &menu{
+		title: "buffers",
+		items: items,
+		sel:   t.bufferIndex(),
+		pick: func(i int) error {
+			t.showBuffer(i)
+			return nil
+		},
+	}
+}
+
+// bufferCommand handles the commands of the prompt for buffers, reporting
+// whether text is one: new runs a command in a new buffer and close closes
+// the one shown.
+func (t *terminal) bufferCommand(text string) (bool, error) {
+	name, arg, _ := strings.Cut(text, " ")
+	switch name {
+	case "new":
+		if arg = strings.TrimSpace(arg); arg == "" {
+			return true, fmt.Errorf("new needs a command")
+		}
+		return true, t.openBuffer(arg)
+	case "close":
+		return true, t.closeBuffer()
+	}
+	return false, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// child is the command run with plumb -- cmd, its output is the input.
+type child struct {
+	cmd  *exec.Cmd
+	done chan struct{} // closed once the command exited
+}
+
+// startChild runs args with its stdout in the input of b and its stderr in
+// the output pane, or interleaved with its stdout as with 2>&1 when
+// mergeStderr is set.
+func (t *terminal) startChild(b *buffer, args []string) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = t.root
+	cmd.Stdout, cmd.Stderr = w, w
+	if !t.mergeStderr {
+		cmd.Stderr = outputWriter{t}
+	}
+	// in its own process group so the processes it starts are killed too
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		r.Close()
+		return err
+	}
+	c := &child{cmd: cmd, done: make(chan struct{})}
+	b.child = c
+	go func() {
+		defer crash()
+		err := t.copyInput(b.stdin, t.decode(r))
+		r.Close()
+		if werr := cmd.Wait(); werr != nil {
+			err = werr
+		}
+		close(c.done)
+		b.stdin.end(err)
+		t.requestDraw()
+	}()
+	return nil
+}
+
+// rerun kills the command of b and runs it again, its output replacing the
+// input or added to it.
+func (t *terminal) rerun(b *buffer, clear bool) {
+	c := b.child
+	if c == nil {
+		t.message = "no command to run again, start plumb with -- cmd"
+		return
+	}
+	b.stdin.gate.resume()
+	select {
+	case <-c.done:
+	default:
+		c.kill()
+		<-c.done
+	}
+	if clear {
+		b.stdin.reset()
+		b.view.Reset()
+		b.links.reset()
+		b.marks = nil
+		b.lexer, b.sniffed = nil, false
+		b.selline, b.topline, b.visual, b.sel = 0, 0, false, nil
+		t.invalidate()
+	} else {
+		b.stdin.endLine()
+		b.stdin.resume()
+	}
+	if err := t.startChild(b, c.cmd.Args); err != nil {
+		t.report(err)
+	}
+}
+
+// kill stops the command and everything it started.
+func (c *child) kill() {
+	syscall.Kill(-c.cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// exitCode returns the exit code of the command, which is killed if it still
+// runs.
+func (c *child) exitCode() int {
+	select {
+	case <-c.done:
+	default:
+		c.kill()
+		<-c.done
+	}
+	if code := c.cmd.ProcessState.ExitCode(); code >= 0 {
+		return code
+	}
+	return 1
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/satran/plumb"
+)
+
+// plumbArgs plumbs each argument without starting the interface, as in
+// plumb main.go:42 or plumb https://example.com. Relative paths are
+// resolved against dir.
+func (t *terminal) plumbArgs(args []string, dir string) error {
+	for _, arg := range args {
+		m := plumb.MatchTarget(t.rules, arg, dir)
+		if m == nil {
+			return fmt.Errorf("%s: no rule matches", arg)
+		}
+		cmd, err := t.command(m)
+		if err != nil {
+			return err
+		}
+		if t.dryrun {
+			fmt.Println(describe(cmd))
+			continue
+		}
+		logInfo("run: %s %#v", cmd.Path, cmd.Args)
+		t.record(m)
+		if cmd.Stdin == nil {
+			cmd.Stdin = os.Stdin
+		}
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %v", cmd.Args[0], err)
+		}
+	}
+	return nil
+}
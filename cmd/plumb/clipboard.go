@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboards are the commands tried in order to set the clipboard, with
+// the environment variable that has to be set for each.
+var clipboards = []struct {
+	env  string
+	args []string
+}{
+	{"WAYLAND_DISPLAY", []string{"wl-copy"}},
+	{"DISPLAY", []string{"xclip", "-selection", "clipboard"}},
+	{"DISPLAY", []string{"xsel", "--clipboard", "--input"}},
+}
+
+// copyText puts s on the system clipboard with pbcopy, wl-copy, xclip or
+// xsel, whichever is there for the display, or else asks the terminal to
+// with an OSC 52 escape sequence, which works over ssh too.
+func copyText(s string) error {
+	if runtime.GOOS == "darwin" {
+		if _, err := exec.LookPath("pbcopy"); err == nil {
+			return pipe(s, "pbcopy")
+		}
+	}
+	for _, c := range clipboards {
+		if os.Getenv(c.env) == "" {
+			continue
+		}
+		if _, err := exec.LookPath(c.args[0]); err == nil {
+			return pipe(s, c.args...)
+		}
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return errors.New("no clipboard")
+	}
+	defer tty.Close()
+	_, err = fmt.Fprintf(tty, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(s)))
+	return err
+}
+
+func pipe(s string, args ...string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(s)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	return nil
+}
+
+// copySelection copies what mode asks for of the selection.
+func (t *terminal) copySelection(mode string) {
+	lines := t.selected(mode)
+	if len(lines) == 0 {
+		t.message = "no file found on this line"
+		return
+	}
+	if err := copyText(strings.Join(lines, "\n")); err != nil {
+		t.report(err)
+		return
+	}
+	t.visual = false
+	if len(lines) == 1 {
+		t.message = "copied " + lines[0]
+	} else {
+		t.message = fmt.Sprintf("copied %d lines", len(lines))
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/satran/plumb"
+)
+
+// config holds the settings read from the config file.
+type config struct {
+	editor    string
+	tabwidth  int
+	tmux      string            // tmux command making the editor's pane
+	detach    bool              // the editor doesn't use the terminal
+	server    string            // vim server name or nvim address
+	syntax    string            // chroma style, or off
+	highlight bool              // highlight the input too
+	links     bool              // mark the plumbable tokens of all lines
+	split     bool              // show what term commands write in the pane
+	numbers   string            // line number mode
+	rules     string            // path of the rules file
+	keymap    string            // default or vi
+	binds     map[string]string // keys bound in addition to the key map
+	colors    map[string]style
+	// openers maps a file type to the command opening it instead of the
+	// editor. The type is an extension like png, a MIME type like
+	// image/png or image/*, or dir for directories.
+	openers map[string]string
+}
+
+// style is the foreground and background a part of the screen is drawn with.
+type style struct {
+	fg, bg attr
+}
+
+func defaultConfig() *config {
+	return &config{
+		editor:   os.Getenv("EDITOR"),
+		tabwidth: 8,
+		syntax:   "monokai",
+		keymap:   "default",
+		links:    true,
+		rules:    filepath.Join(plumb.ConfigDir(), "rules"),
+		colors: map[string]style{
+			"selection": {colorDefault | attrReverse, colorDefault},
+			"search":    {colorBlack, colorYellow},
+			"status":    {colorDefault | attrReverse, colorDefault},
+			"error":     {colorWhite | attrBold, colorRed},
+			"token":     {colorDefault | attrUnderline | attrBold, colorDefault},
+			"gutter":    {colorYellow, colorDefault},
+			"link":      {colorDefault | attrUnderline, colorDefault},
+			"visited":   {paletteColor(8), colorDefault},
+		},
+		openers: map[string]string{},
+		binds:   map[string]string{},
+	}
+}
+
+// loadConfig reads the config file at path over the defaults. A missing
+// config file is not an error.
+func loadConfig(path string) (*config, error) {
+	c := defaultConfig()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := c.parse(path, f); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parse reads key = value lines. Lines starting with # are comments.
+func (c *config) parse(name string, r io.Reader) error {
+	s := bufio.NewScanner(r)
+	lineno := 0
+	for s.Scan() {
+		lineno++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("%s:%d: expected key = value", name, lineno)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if err := c.set(key, value); err != nil {
+			return fmt.Errorf("%s:%d: %v", name, lineno, err)
+		}
+	}
+	return s.Err()
+}
+
+func (c *config) set(key, value string) error {
+	switch {
+	case key == "editor":
+		c.editor = value
+	case key == "tmux":
+		c.tmux = value
+	case key == "server":
+		c.server = value
+	case key == "syntax":
+		if value != "off" && styles.Registry[value] == nil {
+			return fmt.Errorf("unknown syntax style %q", value)
+		}
+		c.syntax = value
+	case key == "highlight":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid highlight %q", value)
+		}
+		c.highlight = b
+	case key == "links":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid links %q", value)
+		}
+		c.links = b
+	case key == "split":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid split %q", value)
+		}
+		c.split = b
+	case key == "numbers":
+		if value != numbersOff && value != numbersAbsolute && value != numbersRelative {
+			return fmt.Errorf("invalid numbers %q", value)
+		}
+		c.numbers = value
+	case key == "detach":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid detach %q", value)
+		}
+		c.detach = b
+	case key == "tabwidth":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid tabwidth %q", value)
+		}
+		c.tabwidth = n
+	case key == "keymap":
+		if _, ok := keymaps[value]; !ok {
+			return fmt.Errorf("unknown keymap %q", value)
+		}
+		c.keymap = value
+	case key == "rules":
+		if strings.HasPrefix(value, "~/") {
+			value = filepath.Join(os.Getenv("HOME"), value[2:])
+		}
+		c.rules = value
+	case strings.HasPrefix(key, "bind."):
+		keys := strings.TrimPrefix(key, "bind.")
+		if err := checkBinding(keys, value); err != nil {
+			return err
+		}
+		c.binds[keys] = value
+	case strings.HasPrefix(key, "open."):
+		c.openers[strings.TrimPrefix(key, "open.")] = value
+	case strings.HasPrefix(key, "color."):
+		name := strings.TrimPrefix(key, "color.")
+		if _, ok := c.colors[name]; !ok {
+			return fmt.Errorf("unknown color %q", name)
+		}
+		st, err := parseStyle(value)
+		if err != nil {
+			return err
+		}
+		c.colors[name] = st
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+var colorNames = map[string]attr{
+	"default":   colorDefault,
+	"black":     colorBlack,
+	"red":       colorRed,
+	"green":     colorGreen,
+	"yellow":    colorYellow,
+	"blue":      colorBlue,
+	"magenta":   colorMagenta,
+	"cyan":      colorCyan,
+	"white":     colorWhite,
+	"bold":      attrBold,
+	"underline": attrUnderline,
+	"reverse":   attrReverse,
+}
+
+// parseStyle parses fg/bg where each side is a color optionally followed by
+// attributes, e.g. red+bold/default. Colors are named, a number of the 256
+// color palette or #rrggbb.
+func parseStyle(s string) (style, error) {
+	var st style
+	parts := strings.SplitN(s, "/", 2)
+	for i, part := range parts {
+		var a attr
+		for _, name := range strings.Split(part, "+") {
+			c, err := parseColor(strings.TrimSpace(name))
+			if err != nil {
+				return st, err
+			}
+			a |= c
+		}
+		if i == 0 {
+			st.fg = a
+		} else {
+			st.bg = a
+		}
+	}
+	return st, nil
+}
+
+// parseColor parses a color or attribute name, a palette number or #rrggbb.
+func parseColor(name string) (attr, error) {
+	if c, ok := colorNames[name]; ok {
+		return c, nil
+	}
+	if n, err := strconv.Atoi(name); err == nil && n >= 0 && n < 256 {
+		return paletteColor(n), nil
+	}
+	if rgb, err := strconv.ParseUint(strings.TrimPrefix(name, "#"), 16, 32); err == nil && len(name) == 7 && name[0] == '#' {
+		return rgbColor(int(rgb>>16), int(rgb>>8), int(rgb)), nil
+	}
+	return 0, fmt.Errorf("unknown color %q", name)
+}
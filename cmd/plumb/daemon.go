@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// socketPath is where the daemon listens for messages. Without
+// $XDG_RUNTIME_DIR it is in a directory of the user's own in /tmp, which is
+// checked as another user could have made it first.
+func socketPath() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "plumb.sock"), nil
+	}
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("plumb-%d", os.Getuid()))
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !fi.IsDir() || !ok || int(st.Uid) != os.Getuid() || fi.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("%s: not a directory only the user can use", dir)
+	}
+	return filepath.Join(dir, "plumb.sock"), nil
+}
+
+// serve listens on the socket and plumbs the messages sent to it, one at a
+// time. A message is a line holding the sender's working directory and the
+// text to plumb separated by a tab, the reply is ok or the error.
+func (t *terminal) serve() error {
+	path, err := socketPath()
+	if err != nil {
+		return err
+	}
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return fmt.Errorf("%s: a daemon is already listening", path)
+	}
+	os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	var mu sync.Mutex
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer crash()
+			defer c.Close()
+			s := bufio.NewScanner(c)
+			for s.Scan() {
+				mu.Lock()
+				err := t.receive(s.Text())
+				mu.Unlock()
+				reply := "ok"
+				if err != nil {
+					reply = err.Error()
+				}
+				fmt.Fprintln(c, reply)
+			}
+		}()
+	}
+}
+
+// receive plumbs a single message.
+func (t *terminal) receive(msg string) error {
+	debug("daemon: %q", msg)
+	wdir, data := "", msg
+	if i := strings.IndexByte(msg, '\t'); i >= 0 {
+		wdir, data = msg[:i], msg[i+1:]
+	}
+	if t.root != "" {
+		wdir = t.root
+	}
+	return t.plumbArgs([]string{data}, wdir)
+}
+
+// send sends args to the daemon to be plumbed.
+func send(args []string) error {
+	path, err := socketPath()
+	if err != nil {
+		return err
+	}
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	wdir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewScanner(c)
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "\t\n") {
+			return fmt.Errorf("%q: messages cannot hold tabs or newlines", arg)
+		}
+		fmt.Fprintf(c, "%s\t%s\n", wdir, arg)
+		if !r.Scan() {
+			return errors.New("daemon closed the connection")
+		}
+		if reply := r.Text(); reply != "ok" {
+			return errors.New(reply)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/satran/plumb"
+)
+
+var (
+	hunkRe = regexp.MustCompile(`^@@ -[0-9]+(,[0-9]+)? \+([0-9]+)(,[0-9]+)? @@`)
+	// diffRule opens locations worked out from the hunks of a unified diff.
+	diffRule = &plumb.Rule{To: "edit"}
+)
+
+// maxHunk bounds how far back a diff line looks for its hunk header.
+const maxHunk = 10000
+
+// diffMatch returns the location in the new file of input line n when it is
+// part of a hunk of a unified diff, or nil.
+func (t *terminal) diffMatch(n int) *plumb.Match {
+	line, err := t.stdin.Line(n)
+	if err != nil || !isHunkLine(line) && !hunkRe.Match(line) {
+		return nil
+	}
+	// count the lines of the new file between the hunk header and n
+	count := 0
+	i := n
+	for ; i >= 0 && n-i < maxHunk; i-- {
+		l, _ := t.stdin.Line(i)
+		if hunkRe.Match(l) {
+			break
+		}
+		if !isHunkLine(l) {
+			return nil
+		}
+		if i < n && !bytes.HasPrefix(l, []byte("-")) {
+			count++
+		}
+	}
+	if i < 0 || n-i >= maxHunk {
+		return nil
+	}
+	header, _ := t.stdin.Line(i)
+	start, _ := strconv.Atoi(string(hunkRe.FindSubmatch(header)[2]))
+	for i--; i >= 0 && n-i < maxHunk; i-- {
+		l, _ := t.stdin.Line(i)
+		if !bytes.HasPrefix(l, []byte("+++ ")) {
+			continue
+		}
+		file := string(l[4:])
+		if j := strings.IndexByte(file, '\t'); j >= 0 {
+			file = file[:j] // diff -u appends a timestamp
+		}
+		if old, _ := t.stdin.Line(i - 1); bytes.HasPrefix(old, []byte("--- a/")) || bytes.Equal(old, []byte("--- /dev/null")) {
+			// git diff, whose paths start with a/ and b/
+			file = strings.TrimPrefix(file, "b/")
+		}
+		if file == "/dev/null" {
+			return nil // deleted
+		}
+		dir := t.dir(n)
+		file, ok := plumb.Resolve(dir, file)
+		if !ok {
+			return nil
+		}
+		line := start + count
+		m := &plumb.Match{
+			Rule:  diffRule,
+			Text:  file + ":" + strconv.Itoa(line),
+			Attrs: map[string]string{"file": file, "line": strconv.Itoa(line), "wdir": dir},
+		}
+		m.Attrs["data"] = m.Text
+		return m
+	}
+	return nil
+}
+
+// isHunkLine reports whether line can be part of the body of a hunk.
+func isHunkLine(line []byte) bool {
+	if len(line) == 0 {
+		return true // some tools strip the space of empty context lines
+	}
+	switch line[0] {
+	case ' ', '+', '-', '\\':
+		return !bytes.HasPrefix(line, []byte("+++ ")) && !bytes.HasPrefix(line, []byte("--- "))
+	}
+	return false
+}
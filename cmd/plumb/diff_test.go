@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffMatch(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b/c.go"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		diff string
+		want string // location of the last line, relative to dir
+	}{
+		{"--- a/a.go\n+++ b/a.go\n@@ -1,2 +3,3 @@\n x\n-y\n+z", "a.go:4"},
+		{"--- /dev/null\n+++ b/a.go\n@@ -0,0 +1,2 @@\n+x\n+y", "a.go:2"},
+		{"--- b/c.go.orig\t2024-01-01\n+++ b/c.go\t2024-01-01\n@@ -1 +1 @@\n+x", "b/c.go:1"},
+		{"--- a/a.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-x", ""},
+		{"--- a/gone.go\n+++ b/gone.go\n@@ -1 +1 @@\n+x", ""},
+	}
+	for _, tt := range tests {
+		in := &lineReader{}
+		in.Write([]byte(tt.diff + "\n"))
+		term := &terminal{buffer: &buffer{stdin: in, view: &view{src: in}}, root: dir}
+		m := term.diffMatch(in.Rows() - 2)
+		got := ""
+		if m != nil {
+			got = strings.TrimPrefix(m.Text, dir+"/")
+		}
+		if got != tt.want {
+			t.Errorf("diffMatch of %q = %q, want %q", tt.diff, got, tt.want)
+		}
+	}
+}
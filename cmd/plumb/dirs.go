@@ -0,0 +1,78 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// dirRe matches the directory changes make prints with -w or when run
+// recursively, e.g. make[1]: Entering directory '/x/y'.
+var dirRe = regexp.MustCompile("^\\S*make(\\[[0-9]+\\])?: (Entering|Leaving) directory [`'\"](.*)['\"]$")
+
+// dirChange records the directory in effect after input line n.
+type dirChange struct {
+	n   int
+	dir string
+}
+
+// trackDir follows make's directory changes on line n, which has just been
+// read completely.
+func (l *lineReader) trackDir(n int) {
+	m := dirRe.FindSubmatch(l.lines.line(n))
+	if m == nil {
+		return
+	}
+	if string(m[2]) == "Entering" {
+		l.dirStack = append(l.dirStack, string(m[3]))
+	} else if len(l.dirStack) > 0 {
+		l.dirStack = l.dirStack[:len(l.dirStack)-1]
+	}
+	dir := ""
+	if len(l.dirStack) > 0 {
+		dir = l.dirStack[len(l.dirStack)-1]
+	}
+	l.dirs = append(l.dirs, dirChange{n, dir})
+}
+
+// enterDir resolves relative paths on the lines written next against dir,
+// and make's directory changes on them against it.
+func (l *lineReader) enterDir(dir string) {
+	l.Lock()
+	defer l.Unlock()
+	n := l.lines.len() - 2 // the last line is the one being written
+	if n < -1 {
+		n = -1
+	}
+	l.dirStack = []string{dir}
+	l.dirs = append(l.dirs, dirChange{n, dir})
+}
+
+// Dir returns the directory make was in when it printed line i, or "" if
+// there is none.
+func (l *lineReader) Dir(i int) string {
+	l.RLock()
+	defer l.RUnlock()
+	j := sort.Search(len(l.dirs), func(j int) bool { return l.dirs[j].n >= i })
+	if j == 0 {
+		return ""
+	}
+	return l.dirs[j-1].dir
+}
+
+// dir returns the directory relative paths on input line n are resolved
+// against, and that actions for them run in. It is made absolute so that
+// paths resolved against it are right from there too.
+func (t *terminal) dir(n int) string {
+	dir := t.stdin.Dir(n)
+	if dir == "" {
+		return t.root
+	}
+	if t.root != "" && !filepath.IsAbs(dir) {
+		return filepath.Join(t.root, dir)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/satran/plumb"
+)
+
+// rowState is everything the drawing of a screen row depends on. Rows whose
+// state didn't change since the last draw are left as they are, the screen
+// keeps their cells.
+type rowState struct {
+	line, part, left, cols int
+	length, spans          int // of the line, the last line grows
+	revision               int // of the line, see lineReader.Revision
+	number                 int // shown in the gutter, or -1
+	gutter                 int
+	selected               bool
+	tokenStart, tokenEnd   int
+	search                 *regexp.Regexp
+	tabwidth               int
+	wrap                   bool
+	highlighted            bool
+	links                  int // tokens marked, -1 while looked for
+	visited                bool
+	count                  int // lines the row stands for, see view.dedup
+}
+
+// rowState returns the state of the row showing part of line n from column
+// left.
+func (t *terminal) rowState(n, part, left int, token *plumb.Match) rowState {
+	line, _ := t.view.Line(n)
+	s := rowState{
+		line:        t.view.Index(n),
+		part:        part,
+		left:        left,
+		cols:        t.cols,
+		length:      len(line),
+		revision:    t.view.src.Revision(t.view.Index(n)),
+		spans:       len(t.view.Spans(n)),
+		number:      -1,
+		gutter:      t.gutter,
+		selected:    n == t.selline || t.inVisual(n),
+		tokenStart:  -1,
+		search:      t.search,
+		tabwidth:    t.tabwidth,
+		wrap:        t.wrap,
+		highlighted: t.highlight && t.lexer != nil,
+		links:       t.lineLinks(n).count(),
+		visited:     t.isVisited(n),
+		count:       t.view.Count(n),
+	}
+	if t.gutter > 0 && part == 0 {
+		s.number = s.line
+		if t.numbers == numbersRelative {
+			s.number = n - t.selline
+		}
+	}
+	if token != nil && n == t.sel.row {
+		s.tokenStart, s.tokenEnd = token.Start, token.End
+	}
+	return s
+}
+
+// dirty reports whether row y has to be drawn in state s, and records it as
+// drawn.
+func (t *terminal) dirty(y int, s rowState) bool {
+	for len(t.drawn) <= y {
+		t.drawn = append(t.drawn, rowState{line: -2})
+	}
+	if t.drawn[y] == s {
+		return false
+	}
+	t.drawn[y] = s
+	return true
+}
+
+// invalidate has every row drawn again, after something was drawn over
+// them.
+func (t *terminal) invalidate() {
+	t.drawn = nil
+}
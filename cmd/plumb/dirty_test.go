@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestDirtyCarriageReturn(t *testing.T) {
+	in := &lineReader{}
+	term := &terminal{buffer: &buffer{stdin: in, view: &view{src: in}}, cols: 80}
+	in.Write([]byte("done\ndownloading 10%"))
+	if !term.dirty(1, term.rowState(1, 0, 0, nil)) {
+		t.Fatal("new row not drawn")
+	}
+	if term.dirty(1, term.rowState(1, 0, 0, nil)) {
+		t.Fatal("unchanged row drawn again")
+	}
+	in.Write([]byte("\rdownloading 20%"))
+	if line, _ := term.view.Line(1); string(line) != "downloading 20%" {
+		t.Fatalf("line = %q, want the text after the carriage return", line)
+	}
+	if !term.dirty(1, term.rowState(1, 0, 0, nil)) {
+		t.Error("row rewritten as long as it was not drawn again")
+	}
+	term.dirty(0, term.rowState(0, 0, 0, nil))
+	in.Write([]byte("\rdownloading 30%"))
+	if term.dirty(0, term.rowState(0, 0, 0, nil)) {
+		t.Error("row above the rewritten one drawn again")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"mime"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/satran/plumb"
+)
+
+// opener returns the command configured for the type of file, trying the
+// extension, the MIME type and then its major type.
+func (t *terminal) opener(file string) (string, bool) {
+	if fi, err := os.Stat(file); err == nil && fi.IsDir() {
+		cmd, ok := t.openers["dir"]
+		return cmd, ok
+	}
+	ext := filepath.Ext(file)
+	if ext == "" {
+		return "", false
+	}
+	if cmd, ok := t.openers[ext[1:]]; ok {
+		return cmd, true
+	}
+	typ := mime.TypeByExtension(ext)
+	if i := strings.Index(typ, ";"); i >= 0 {
+		typ = typ[:i]
+	}
+	if typ == "" {
+		return "", false
+	}
+	if cmd, ok := t.openers[typ]; ok {
+		return cmd, true
+	}
+	cmd, ok := t.openers[typ[:strings.Index(typ, "/")]+"/*"]
+	return cmd, ok
+}
+
+// noWait adds -n to emacsclient so it returns once the file is open in
+// emacs, unless it is to use the terminal.
+func noWait(args []string) []string {
+	if filepath.Base(args[0]) != "emacsclient" {
+		return args
+	}
+	for _, a := range args[1:] {
+		switch a {
+		case "-n", "--no-wait", "-t", "-nw", "--tty":
+			return args
+		}
+	}
+	return append([]string{args[0], "-n"}, args[1:]...)
+}
+
+// detached reports whether cmd opens its file without the terminal and
+// returns, so it isn't handed the terminal: emacsclient -n, files sent to
+// a running vim, the platform's opener, editors run in a tmux pane, or the
+// editor when detach is set.
+func (t *terminal) detached(cmd *exec.Cmd) bool {
+	for _, a := range cmd.Args[1:] {
+		switch a {
+		case "-n", "--no-wait":
+			if filepath.Base(cmd.Args[0]) == "emacsclient" {
+				return true
+			}
+		case "--remote-send", "--remote-silent":
+			return true
+		}
+	}
+	if cmd.Args[0] == plumb.Opener() {
+		return true
+	}
+	if tmux := strings.Fields(t.tmux); len(tmux) > 0 && cmd.Args[0] == "tmux" && len(cmd.Args) > 1 && cmd.Args[1] == tmux[0] {
+		return true
+	}
+	editor := strings.Fields(t.editor)
+	return t.detach && len(editor) > 0 && cmd.Args[0] == editor[0]
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// inputEncoding returns the encoding called name, as in the WHATWG encoding
+// standard, such as latin1, windows-1252 or shift_jis. UTF-16 follows a byte
+// order mark and is little endian without one unless asked for big endian.
+func inputEncoding(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(strings.ReplaceAll(name, "-", "")) {
+	case "utf16", "utf16le":
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), nil
+	case "utf16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM), nil
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	return enc, nil
+}
+
+// decode returns r converted to UTF-8 from the encoding of the input.
+func (t *terminal) decode(r io.Reader) io.Reader {
+	if t.encoding == nil {
+		return r
+	}
+	return transform.NewReader(r, t.encoding.NewDecoder())
+}
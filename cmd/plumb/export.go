@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/satran/plumb"
+)
+
+// Export formats of the targets.
+const (
+	exportQuickfix = "quickfix" // file:line:col: text as read by vim -q
+	exportJSON     = "json"     // an object per line, see exported
+)
+
+// exported is a target as written in the JSON format.
+type exported struct {
+	Rule      string `json:"rule,omitempty"`
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Col       int    `json:"col,omitempty"`
+	Text      string `json:"text"`
+	To        string `json:"to,omitempty"`
+	Input     string `json:"input"`      // the line the target was found on
+	InputLine int    `json:"input_line"` // its number
+}
+
+func newExported(m *plumb.Match, line string) exported {
+	return exported{
+		Rule:  m.Rule.Name,
+		File:  m.Attrs["file"],
+		Line:  m.Line(),
+		Col:   m.Col(),
+		Text:  m.Text,
+		To:    m.Rule.To,
+		Input: line,
+	}
+}
+
+// exportTargets writes the targets of the lines shown to w in format and
+// returns how many it wrote. Quickfix lists only have files, with the line
+// they were found on as the text.
+func (t *terminal) exportTargets(w io.Writer, format string) (int, error) {
+	if format != exportQuickfix && format != exportJSON {
+		return 0, fmt.Errorf("export format is %s or %s, not %q", exportQuickfix, exportJSON, format)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	n := 0
+	for row := 0; row < t.view.Rows(); row++ {
+		if ll := t.lineLinks(row); ll != nil && len(ll.tokens) == 0 {
+			continue
+		}
+		b, _ := t.view.Line(row)
+		line := strings.TrimSpace(string(b))
+		ms, _ := t.lineMatches(row)
+		for _, m := range ms {
+			var err error
+			switch {
+			case format == exportJSON:
+				e := newExported(m, line)
+				e.InputLine = t.view.Index(row) + 1
+				err = enc.Encode(e)
+			case m.Attrs["file"] != "":
+				l := m.Line()
+				if l == 0 {
+					l = 1
+				}
+				_, err = fmt.Fprintf(w, "%s: %s\n", location(m.Attrs["file"], l, m.Col()), line)
+			default:
+				continue
+			}
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// export writes the targets to the file name in format, or for - to stdout
+// on exit.
+func (t *terminal) export(name, format string) (int, error) {
+	if name == "-" {
+		var buf bytes.Buffer
+		n, err := t.exportTargets(&buf, format)
+		if s := strings.TrimSuffix(buf.String(), "\n"); s != "" {
+			t.output = append(t.output, s)
+		}
+		return n, err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := t.exportTargets(f, format)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// exportCommand handles the export command of the prompt, reporting whether
+// text is one: export writes the targets to a file, the one of -export when
+// none is given, in the format of -export-format.
+func (t *terminal) exportCommand(text string) (bool, error) {
+	name, arg, _ := strings.Cut(text, " ")
+	if name != "export" {
+		return false, nil
+	}
+	file, format := strings.TrimSpace(arg), t.exportFormat
+	if file == "" {
+		file = t.exportFile
+	}
+	if file == "" {
+		return true, fmt.Errorf("export needs a file, or - for stdout")
+	}
+	n, err := t.export(file, format)
+	if err != nil {
+		return true, err
+	}
+	t.message = fmt.Sprintf("exported %d targets to %s", n, file)
+	if file == "-" {
+		t.message = fmt.Sprintf("%d targets are written to stdout on exit", n)
+	}
+	return true, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// readFiles adds the named files to the input one after the other, headed by
+// their names when there are several as head(1) does. Relative paths in a
+// file are resolved against its directory, - reads stdin.
+func (t *terminal) readFiles(in *lineReader, names []string) {
+	defer crash()
+	var failed error
+	for i, name := range names {
+		if len(names) > 1 {
+			if i > 0 {
+				in.Write([]byte("\n"))
+			}
+			fmt.Fprintf(in, "==> %s <==\n", name)
+		}
+		if err := t.readFile(in, name); err != nil && failed == nil {
+			failed = err
+		}
+		in.endLine()
+	}
+	in.end(failed)
+	t.requestDraw()
+}
+
+// readFile adds the file name to the input.
+func (t *terminal) readFile(in *lineReader, name string) error {
+	if name == "-" {
+		in.enterDir("")
+		return t.copyInput(in, &binaryGuard{Reader: t.decode(os.Stdin), t: t})
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if dir, err := filepath.Abs(filepath.Dir(name)); err == nil {
+		in.enterDir(dir)
+	}
+	return t.copyInput(in, &binaryGuard{Reader: t.decode(f), t: t})
+}
+
+// endLine ends the last line if it has text, for input that doesn't end with
+// a newline.
+func (l *lineReader) endLine() {
+	l.RLock()
+	n := l.lines.len()
+	partial := n > 0 && len(l.lines.line(n-1)) > 0
+	l.RUnlock()
+	if partial {
+		l.Write([]byte("\n"))
+	}
+}
+
+// appendLines adds p after the line being read, which is ended first if it
+// has text, so that input still arriving doesn't run into p.
+func (l *lineReader) appendLines(p []byte) {
+	l.Lock()
+	defer l.Unlock()
+	if n := l.lines.len(); n > 0 && len(l.lines.line(n-1)) > 0 {
+		l.write([]byte("\n"))
+	}
+	l.write(p)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// lineFilter shows only the lines its pattern matches, such as the one of
+// -g, or with exclude only the lines it does not, as -v. Filters can be
+// turned off to show the lines they hide.
+type lineFilter struct {
+	re      *regexp.Regexp
+	exclude bool
+	off     bool
+}
+
+// pass reports whether line is shown by f.
+func (f *lineFilter) pass(line []byte) bool {
+	return f.re.Match(line) != f.exclude
+}
+
+func (f *lineFilter) String() string {
+	s := "on  "
+	if f.off {
+		s = "off "
+	}
+	if f.exclude {
+		return s + "out " + f.re.String()
+	}
+	return s + "in  " + f.re.String()
+}
+
+// filterFlag is the -g or -v flag, which can be given more than once. Both
+// add to the same filters, in the order given.
+type filterFlag struct {
+	filters *[]*lineFilter
+	exclude bool
+}
+
+func (f filterFlag) String() string {
+	if f.filters == nil {
+		return ""
+	}
+	var s []string
+	for _, lf := range *f.filters {
+		if lf.exclude == f.exclude {
+			s = append(s, lf.re.String())
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+func (f filterFlag) Set(s string) error {
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return err
+	}
+	*f.filters = append(*f.filters, &lineFilter{re: re, exclude: f.exclude})
+	return nil
+}
+
+// narrow shows the lines that pass the filters and match, unless it is nil.
+// The selection stays on the same input line, or the first shown after it.
+func (t *terminal) narrow(match func(line []byte) bool) {
+	var on []*lineFilter
+	for _, f := range t.filters {
+		if !f.off {
+			on = append(on, f)
+		}
+	}
+	n := t.view.Index(t.selline)
+	if len(on) == 0 && match == nil {
+		t.view.SetFilter(nil)
+	} else {
+		t.view.SetFilter(func(line []byte) bool {
+			for _, f := range on {
+				if !f.pass(line) {
+					return false
+				}
+			}
+			return match == nil || match(line)
+		})
+	}
+	if n >= 0 {
+		t.gotoLine(t.view.Find(n))
+	}
+}
+
+// shown reports whether line passes the filters that are on.
+func (t *terminal) shown(line []byte) bool {
+	for _, f := range t.filters {
+		if !f.off && !f.pass(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// toggleDedup shows runs of identical lines once with their number, or every
+// line again, keeping the selection on the same input line.
+func (t *terminal) toggleDedup() {
+	n := t.view.Index(t.selline)
+	on := !t.view.dedup
+	t.view.SetDedup(on)
+	if n >= 0 {
+		t.gotoLine(t.view.Find(n))
+	}
+	if on {
+		t.message = "repeated lines collapsed"
+	} else {
+		t.message = "repeated lines shown"
+	}
+}
+
+// drawCount draws the number of lines a row stands for on row y after its
+// text ending at column end, or at the right edge if the text goes past it.
+func (t *terminal) drawCount(y, count, end, cols int) {
+	label := []rune(fmt.Sprintf(" ×%d", count))
+	if !t.wrap && end+len(label) >= cols {
+		// left of the arrow marking the text cut off
+		end = cols - len(label) - 1
+	}
+	if end < 0 || end+len(label) > cols {
+		return
+	}
+	st := t.colors["gutter"]
+	for i, r := range label {
+		screen.SetCell(t.gutter+end+i, y, r, st.fg, st.bg)
+	}
+}
+
+// toggleFilters turns the filters off to show the lines around the ones
+// they let through, or on again.
+func (t *terminal) toggleFilters() {
+	if len(t.filters) == 0 {
+		t.message = "no filters, start plumb with -g or -v pattern"
+		return
+	}
+	off := !t.filters[0].off
+	for _, f := range t.filters {
+		f.off = off
+	}
+	t.narrow(nil)
+	if off {
+		t.message = "filters off"
+	} else {
+		t.message = "filters on"
+	}
+}
+
+// showFilters opens the menu of the filters, picking one turns it off or on.
+func (t *terminal) showFilters(sel int) {
+	if len(t.filters) == 0 {
+		t.message = "no filters, add one with :filter-in or :filter-out pattern"
+		return
+	}
+	items := make([]string, len(t.filters))
+	for i, f := range t.filters {
+		items[i] = f.String()
+	}
+	t.menu = &menu{
+		title: "filters",
+		items: items,
+		sel:   sel,
+		pick: func(i int) error {
+			t.filters[i].off = !t.filters[i].off
+			t.narrow(nil)
+			t.showFilters(i)
+			return nil
+		},
+	}
+}
+
+// filterCommand handles the commands of the prompt changing the filters,
+// reporting whether text is one: filter-in and filter-out add a filter
+// showing or hiding the lines matching a pattern, filter-pop removes the
+// last one added.
+func (t *terminal) filterCommand(text string) (bool, error) {
+	name, arg, _ := strings.Cut(text, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "filter-in", "filter-out":
+		if arg == "" {
+			return true, fmt.Errorf("%s needs a pattern", name)
+		}
+		if err := (filterFlag{&t.filters, name == "filter-out"}).Set(arg); err != nil {
+			return true, err
+		}
+	case "filter-pop":
+		if len(t.filters) == 0 {
+			return true, fmt.Errorf("no filters")
+		}
+		t.filters = t.filters[:len(t.filters)-1]
+	default:
+		return false, nil
+	}
+	t.narrow(nil)
+	return true, nil
+}
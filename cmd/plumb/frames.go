@@ -0,0 +1,34 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/satran/plumb"
+)
+
+// frameRe matches the lines of a Go stack trace that name a goroutine or a
+// function and whose location is on one of the following lines:
+//
+//	goroutine 1 [running]:
+//	main.main()
+//		/home/u/src/pkg/main.go:12 +0x1f
+var frameRe = regexp.MustCompile(`^(goroutine \d+ \[.*\]:|created by \S+.*|\S+\(.*\))$`)
+
+// frameLookahead is the number of lines after a frame line searched for its
+// location.
+const frameLookahead = 2
+
+// frameMatches returns the matches of the first line after the frame line
+// on row that has any, and the row of that line.
+func (t *terminal) frameMatches(row int) ([]*plumb.Match, int) {
+	for i := 1; i <= frameLookahead; i++ {
+		line, err := t.view.Line(row + i)
+		if err != nil {
+			break
+		}
+		if m := plumb.MatchLine(t.rules, string(line), t.dir(t.view.Index(row+i))); len(m) > 0 {
+			return m, row + i
+		}
+	}
+	return nil, row
+}
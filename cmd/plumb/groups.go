@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/satran/plumb"
+)
+
+// occurrence is a target found on a row of the view.
+type occurrence struct {
+	row int
+	m   *plumb.Match
+}
+
+// fileGroup is the occurrences of a file among the targets.
+type fileGroup struct {
+	file string
+	occs []occurrence
+}
+
+// groupFiles returns the file targets of the lines shown by file, in the
+// order they first appear, and whether all lines were looked through.
+func (t *terminal) groupFiles() ([]*fileGroup, bool) {
+	var groups []*fileGroup
+	byFile := map[string]*fileGroup{}
+	rows := t.view.Rows()
+	n := 0
+	for ; n < rows && n < maxScan; n++ {
+		if ll := t.lineLinks(n); ll != nil && len(ll.tokens) == 0 {
+			continue
+		}
+		ms, row := t.lineMatches(n)
+		for _, m := range ms {
+			f := m.Attrs["file"]
+			if f == "" {
+				continue
+			}
+			g := byFile[f]
+			if g == nil {
+				g = &fileGroup{file: f}
+				byFile[f] = g
+				groups = append(groups, g)
+			}
+			g.occs = append(g.occs, occurrence{row, m})
+		}
+	}
+	return groups, n == rows
+}
+
+// showFiles opens the menu of the files among the targets with how often
+// each occurs. Picking one lists its occurrences to plumb one of them.
+func (t *terminal) showFiles() {
+	groups, all := t.groupFiles()
+	if len(groups) == 0 {
+		t.message = "no files among the targets"
+		return
+	}
+	title := "files"
+	if !all {
+		title = fmt.Sprintf("files in the first %d lines", maxScan)
+	}
+	items := make([]string, len(groups))
+	for i, g := range groups {
+		items[i] = fmt.Sprintf("%4d  %s", len(g.occs), g.file)
+	}
+	var files *menu
+	files = &menu{
+		title: title,
+		items: items,
+		pick: func(i int) error {
+			t.showOccurrences(groups[i], func() { t.menu = files })
+			return nil
+		},
+	}
+	t.menu = files
+}
+
+// showOccurrences opens the menu of the occurrences of a file, picking one
+// selects its line and plumbs it. back reopens the menu of the files.
+func (t *terminal) showOccurrences(g *fileGroup, back func()) {
+	items := make([]string, len(g.occs))
+	for i, o := range g.occs {
+		line, _ := t.view.Line(o.row)
+		pos := o.m.Attrs["line"]
+		if pos == "" {
+			pos = "-"
+		}
+		if c := o.m.Attrs["col"]; c != "" {
+			pos += ":" + c
+		}
+		items[i] = fmt.Sprintf("%-8s %s", pos, strings.TrimSpace(string(line)))
+	}
+	t.menu = &menu{
+		title: fmt.Sprintf("%s (%d)", g.file, len(g.occs)),
+		items: items,
+		back:  back,
+		pick: func(i int) error {
+			o := g.occs[i]
+			t.gotoLine(o.row)
+			return t.plumb(o.m)
+		},
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Line number modes.
+const (
+	numbersOff      = "off"
+	numbersAbsolute = "absolute" // input line numbers
+	numbersRelative = "relative" // distance from the selected line
+)
+
+// Time stamp modes.
+const (
+	stampsOff      = "off"
+	stampsAbsolute = "absolute" // time of day the line arrived
+	stampsRelative = "relative" // time since plumb started
+)
+
+// gutterWidth returns the columns taken by time stamps and line numbers left
+// of the lines.
+func (t *terminal) gutterWidth() int {
+	w := t.stampWidth()
+	if t.numbers == "" || t.numbers == numbersOff {
+		return w
+	}
+	n := len(strconv.Itoa(t.stdin.Rows()))
+	if n < 3 {
+		n = 3
+	}
+	return w + n + 1
+}
+
+// stampWidth returns the columns taken by time stamps.
+func (t *terminal) stampWidth() int {
+	switch t.stamps {
+	case stampsAbsolute:
+		return len("15:04:05.000 ")
+	case stampsRelative:
+		return len("+00:00:00.000 ")
+	}
+	return 0
+}
+
+// drawNumber draws the time stamp and line number of row n on screen row y,
+// or leaves them blank for n -1.
+func (t *terminal) drawNumber(y, n int) {
+	if t.gutter == 0 {
+		return
+	}
+	st := t.colors["gutter"]
+	sw := t.stampWidth()
+	s := strings.Repeat(" ", t.gutter)
+	if n >= 0 && n < t.view.Rows() {
+		s = t.stamp(n) + t.number(n, t.gutter-sw)
+	}
+	for x, r := range s {
+		screen.SetCell(x, y, r, st.fg, st.bg)
+	}
+}
+
+// number formats the line number of row n w columns wide, or nothing when
+// line numbers are off.
+func (t *terminal) number(n, w int) string {
+	if w == 0 {
+		return ""
+	}
+	num := t.view.Index(n) + 1
+	if t.numbers == numbersRelative && n != t.selline {
+		num = n - t.selline
+		if num < 0 {
+			num = -num
+		}
+	}
+	return fmt.Sprintf("%*d ", w-1, num)
+}
+
+// stamp formats the time row n arrived at, blank for lines that didn't yet,
+// or nothing when time stamps are off.
+func (t *terminal) stamp(n int) string {
+	w := t.stampWidth()
+	if w == 0 {
+		return ""
+	}
+	at := t.stdin.Arrived(t.view.Index(n))
+	if at.IsZero() {
+		return strings.Repeat(" ", w)
+	}
+	if t.stamps == stampsAbsolute {
+		return at.Format("15:04:05.000 ")
+	}
+	d := at.Sub(t.started)
+	ms := d.Milliseconds()
+	return fmt.Sprintf("+%02d:%02d:%02d.%03d ", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// toggleStamps cycles through no time stamps, absolute and relative ones.
+func (t *terminal) toggleStamps() {
+	switch t.stamps {
+	case stampsAbsolute:
+		t.stamps = stampsRelative
+	case stampsRelative:
+		t.stamps = stampsOff
+	default:
+		t.stamps = stampsAbsolute
+	}
+}
+
+// toggleNumbers cycles through no line numbers, absolute and relative ones.
+func (t *terminal) toggleNumbers() {
+	switch t.numbers {
+	case numbersAbsolute:
+		t.numbers = numbersRelative
+	case numbersRelative:
+		t.numbers = numbersOff
+	default:
+		t.numbers = numbersAbsolute
+	}
+}
+
+// commands handle the commands of the prompt, reporting whether text is one
+// of theirs.
+var commands = []func(t *terminal, text string) (bool, error){
+	(*terminal).filterCommand,
+	(*terminal).exportCommand,
+	(*terminal).bufferCommand,
+	(*terminal).sortCommand,
+	(*terminal).saveCommand,
+}
+
+// startCommand opens the prompt for commands. A number goes to that line of
+// the input, the others are handled by the functions of commands.
+func (t *terminal) startCommand() {
+	t.prompt = &prompt{
+		label: ":",
+		done: func(text string) error {
+			text = strings.TrimSpace(text)
+			if n, err := strconv.Atoi(text); err == nil {
+				t.gotoLine(t.view.Find(n - 1))
+				return nil
+			}
+			for _, command := range commands {
+				if ok, err := command(t, text); ok {
+					if err != nil {
+						t.report(err)
+					}
+					return nil
+				}
+			}
+			if text != "" {
+				t.report(fmt.Errorf("unknown command %q", text))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// help lists the key bindings and the rules over the lines, or other lines
+// under its title such as the jobs.
+type help struct {
+	title string
+	lines []string
+	top   int
+}
+
+// showHelp opens the help for the bindings and rules in use.
+func (t *terminal) showHelp() {
+	byAction := map[string][]string{}
+	var names []string
+	for k, a := range t.keys {
+		if byAction[a] == nil {
+			names = append(names, a)
+		}
+		byAction[a] = append(byAction[a], k)
+	}
+	sort.Strings(names)
+	h := &help{title: "help", lines: []string{"keys"}}
+	for _, a := range names {
+		keys := byAction[a]
+		sort.Strings(keys)
+		h.lines = append(h.lines, fmt.Sprintf("  %-12s %s", a, strings.Join(keys, ", ")))
+	}
+	h.lines = append(h.lines, "", "rules")
+	for _, r := range t.rules {
+		action := "plumb to " + r.To
+		if r.Start != nil {
+			action = "plumb start " + strings.Join(r.Start, " ")
+		}
+		h.lines = append(h.lines, "  "+r.Pattern.String(), "    "+action)
+	}
+	t.help = h
+}
+
+// key scrolls the help with the movement keys and reports whether another
+// key closed it.
+func (h *help) key(ev event, rows int) bool {
+	switch ev.Key {
+	case "up":
+		h.top--
+	case "down":
+		h.top++
+	case "pgup":
+		h.top -= rows
+	case "pgdn":
+		h.top += rows
+	default:
+		return true
+	}
+	if h.top > len(h.lines)-rows {
+		h.top = len(h.lines) - rows
+	}
+	if h.top < 0 {
+		h.top = 0
+	}
+	return false
+}
+
+// height returns the rows the help shows on a screen of rows.
+func (h *help) height(rows int) int {
+	if n := rows - 4; len(h.lines) > n {
+		return n
+	}
+	return len(h.lines)
+}
+
+// draw draws the help in a box in the middle of a screen of cols by rows.
+func (h *help) draw(cols, rows int) {
+	w := 0
+	for _, l := range h.lines {
+		if n := len([]rune(l)) + 4; n > w {
+			w = n
+		}
+	}
+	if w > cols-2 {
+		w = cols - 2
+	}
+	n := h.height(rows)
+	title := h.title
+	if n < len(h.lines) {
+		title += ", up and down scroll"
+	}
+	x0, y0, text := drawBox(title, w, n, cols, rows)
+	for i := 0; i < n && h.top+i < len(h.lines); i++ {
+		text(x0+2, y0+1+i, h.lines[h.top+i], colorDefault, colorDefault)
+	}
+	screen.HideCursor()
+}
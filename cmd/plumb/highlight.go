@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// sniffLines is the number of lines of the input its language is guessed
+// from.
+const sniffLines = 50
+
+// highlight returns src with the colors of the syntax style as escape
+// sequences. The language is found from the file name or else guessed from
+// src, unknown languages are returned as is.
+func highlight(name string, src []byte, syntax string) []byte {
+	lexer := lexers.Match(name)
+	if lexer == nil {
+		lexer = lexers.Analyse(string(src))
+	}
+	if lexer == nil {
+		return src
+	}
+	return colorize(lexer, src, syntax)
+}
+
+func colorize(lexer chroma.Lexer, src []byte, syntax string) []byte {
+	it, err := chroma.Coalesce(lexer).Tokenise(nil, string(src))
+	if err != nil {
+		return src
+	}
+	var b bytes.Buffer
+	if err := formatters.TTY16m.Format(&b, styles.Get(syntax), it); err != nil {
+		return src
+	}
+	return b.Bytes()
+}
+
+// inputLexer returns the lexer for the language of the input, guessed from
+// its first lines, or nil. Until enough lines have arrived, or the input
+// ended, the guess isn't kept.
+func (t *terminal) inputLexer() chroma.Lexer {
+	if t.sniffed {
+		return t.lexer
+	}
+	rows := t.stdin.Rows()
+	if rows > sniffLines {
+		rows = sniffLines
+	}
+	var lines []string
+	var lexer chroma.Lexer
+	for i := 0; i < rows; i++ {
+		line, _ := t.stdin.Line(i)
+		if bytes.HasPrefix(line, []byte("diff --git ")) || bytes.HasPrefix(line, []byte("@@ -")) {
+			lexer = lexers.Get("diff")
+		}
+		lines = append(lines, string(line))
+	}
+	if lexer == nil {
+		lexer = lexers.Analyse(strings.Join(lines, "\n"))
+	}
+	_, _, ended, _ := t.stdin.state()
+	if rows == sniffLines || lexer != nil || ended {
+		t.lexer, t.sniffed = lexer, true
+	}
+	return lexer
+}
+
+// syntaxSpans returns the styles of a line of the input as highlighted in
+// the language of the input, or nil.
+func (t *terminal) syntaxSpans(line []byte) []span {
+	lexer := t.inputLexer()
+	if lexer == nil {
+		return nil
+	}
+	l := &lineReader{}
+	l.Write(colorize(lexer, line, t.syntax))
+	return l.Spans(0)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/satran/plumb"
+)
+
+// maxHistory is the most targets the history menu lists.
+const maxHistory = 100
+
+// historyFile is where the targets plumbed are recorded, in
+// $XDG_STATE_HOME/plumb as for other state that outlives a session.
+func historyFile() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".local", "state")
+	}
+	return filepath.Join(dir, "plumb", "history")
+}
+
+// historyTarget is the target m is plumbed again by: its file with the
+// line and column, or its text.
+func historyTarget(m *plumb.Match) string {
+	f := m.Attrs["file"]
+	if f == "" {
+		return m.Text
+	}
+	if !filepath.IsAbs(f) {
+		if abs, err := filepath.Abs(f); err == nil {
+			f = abs
+		}
+	}
+	if l := m.Line(); l > 0 {
+		f += ":" + strconv.Itoa(l)
+		if c := m.Col(); c > 0 {
+			f += ":" + strconv.Itoa(c)
+		}
+	}
+	return f
+}
+
+// record adds m to the history as a line of the time, the directory, the
+// target and the rule's pattern separated by tabs.
+func (t *terminal) record(m *plumb.Match) {
+	name := historyFile()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		logError("history: %v", err)
+		return
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		logError("history: %v", err)
+		return
+	}
+	defer f.Close()
+	pattern := ""
+	if m.Rule.Pattern != nil {
+		pattern = m.Rule.Pattern.String()
+	}
+	clean := func(s string) string { return strings.NewReplacer("\t", " ", "\n", " ").Replace(s) }
+	fmt.Fprintf(f, "%s\t%s\t%s\t%s\n", time.Now().Format(time.RFC3339), clean(m.Attrs["wdir"]), clean(historyTarget(m)), clean(pattern))
+}
+
+// historyEntry is a target read back from the history.
+type historyEntry struct {
+	dir, target string
+}
+
+// readHistory returns the targets of the history, the last plumbed first
+// and each only once.
+func readHistory() ([]historyEntry, error) {
+	f, err := os.Open(historyFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var all []historyEntry
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Split(s.Text(), "\t")
+		if len(fields) < 3 || fields[2] == "" {
+			continue
+		}
+		all = append(all, historyEntry{dir: fields[1], target: fields[2]})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	var entries []historyEntry
+	seen := map[string]bool{}
+	for i := len(all) - 1; i >= 0 && len(entries) < maxHistory; i-- {
+		if !seen[all[i].target] {
+			seen[all[i].target] = true
+			entries = append(entries, all[i])
+		}
+	}
+	return entries, nil
+}
+
+// showHistory opens the menu of the targets plumbed before, in this
+// session or earlier ones, to plumb one again.
+func (t *terminal) showHistory() {
+	entries, err := readHistory()
+	if err != nil {
+		t.report(err)
+		return
+	}
+	if len(entries) == 0 {
+		t.message = "nothing was plumbed yet"
+		return
+	}
+	items := make([]string, len(entries))
+	for i, e := range entries {
+		items[i] = e.target
+	}
+	t.menu = &menu{
+		title: "history",
+		items: items,
+		pick: func(i int) error {
+			e := entries[i]
+			m := plumb.MatchTarget(t.rules, e.target, e.dir)
+			if m == nil {
+				t.message = e.target + ": no rule matches"
+				return nil
+			}
+			if err := t.plumb(m); err != nil {
+				t.report(err)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// maxJobs is the most jobs kept in the job list.
+const maxJobs = 50
+
+// job is an action run in the background, see startJob.
+type job struct {
+	cmd  *exec.Cmd
+	desc string // as by describe
+	done bool
+	err  error
+}
+
+// jobs lists the actions run in the background, oldest first. Jobs finish
+// on their own goroutines, so the list is locked.
+type jobs struct {
+	sync.Mutex
+	list     []*job
+	finished []func() // run by the main loop, see finishJobs
+}
+
+// startJob runs cmd in a session of its own, away from the terminal, and
+// returns without waiting for it. done is called by the main loop once it
+// exited. Commands without output set are detached: their output is only
+// kept for the error they report, which is shown in the status bar unless
+// done is given. Otherwise the error is written after the output.
+func (t *terminal) startJob(cmd *exec.Cmd, done func(err error)) error {
+	var out *bytes.Buffer
+	if cmd.Stdout == nil && cmd.Stderr == nil {
+		out = &bytes.Buffer{}
+		cmd.Stdout, cmd.Stderr = out, out
+		if done == nil {
+			done = func(err error) {
+				if err != nil {
+					t.report(err)
+				}
+			}
+		}
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%s: %v", cmd.Args[0], err)
+	}
+	j := &job{cmd: cmd, desc: describe(cmd)}
+	t.jobs.Lock()
+	t.jobs.list = append(t.jobs.list, j)
+	if len(t.jobs.list) > maxJobs {
+		t.jobs.list = t.jobs.list[1:]
+	}
+	t.jobs.Unlock()
+	go func() {
+		defer crash()
+		err := cmd.Wait()
+		if err != nil && out != nil {
+			if msg := strings.TrimSpace(out.String()); msg != "" {
+				err = errors.New(strings.SplitN(msg, "\n", 2)[0])
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %v", cmd.Args[0], err)
+			if done == nil && cmd.Stderr != nil {
+				fmt.Fprintln(cmd.Stderr, err)
+			}
+		}
+		t.jobs.Lock()
+		j.done, j.err = true, err
+		if done != nil {
+			t.jobs.finished = append(t.jobs.finished, func() { done(err) })
+		}
+		t.jobs.Unlock()
+		t.requestDraw()
+	}()
+	return nil
+}
+
+// finishJobs calls done for the jobs that exited since it was last called.
+func (t *terminal) finishJobs() {
+	t.jobs.Lock()
+	finished := t.jobs.finished
+	t.jobs.finished = nil
+	t.jobs.Unlock()
+	for _, f := range finished {
+		f()
+	}
+}
+
+// killJobs kills the jobs still running and everything they started, for
+// commands that hang.
+func (t *terminal) killJobs() {
+	t.jobs.Lock()
+	defer t.jobs.Unlock()
+	n := 0
+	for _, j := range t.jobs.list {
+		if !j.done {
+			// the job leads its own session and process group
+			syscall.Kill(-j.cmd.Process.Pid, syscall.SIGKILL)
+			n++
+		}
+	}
+	if n == 0 {
+		t.message = "no jobs are running"
+		return
+	}
+	t.message = fmt.Sprintf("killed %d jobs", n)
+}
+
+// showJobs opens the list of jobs with their state.
+func (t *terminal) showJobs() {
+	h := &help{title: "jobs"}
+	t.jobs.Lock()
+	for _, j := range t.jobs.list {
+		state := "running"
+		switch {
+		case j.err != nil:
+			state = "failed"
+		case j.done:
+			state = "finished"
+		}
+		h.lines = append(h.lines, fmt.Sprintf("%-8s %s", state, j.desc))
+		if j.err != nil {
+			h.lines = append(h.lines, "         "+j.err.Error())
+		}
+	}
+	t.jobs.Unlock()
+	if len(h.lines) == 0 {
+		t.message = "no jobs were run"
+		return
+	}
+	t.help = h
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// actions are the commands keys can be bound to.
+var actions = map[string]func(t *terminal) error{
+	"up":       func(t *terminal) error { t.moveCursor("up"); return nil },
+	"down":     func(t *terminal) error { t.moveCursor("down"); return nil },
+	"pgup":     func(t *terminal) error { t.gotoLine(t.selline - t.page()); return nil },
+	"pgdn":     func(t *terminal) error { t.gotoLine(t.selline + t.page()); return nil },
+	"halfpgup": func(t *terminal) error { t.gotoLine(t.selline - t.page()/2); return nil },
+	"halfpgdn": func(t *terminal) error { t.gotoLine(t.selline + t.page()/2); return nil },
+	"top":      func(t *terminal) error { t.gotoLine(0); return nil },
+	"bottom":   func(t *terminal) error { t.gotoLine(t.view.Rows() - 1); return nil },
+	"plumb": func(t *terminal) error {
+		if t.visual && t.print == "" {
+			if err := t.openAll(); err != nil {
+				t.report(err)
+			}
+			return nil
+		}
+		t.exec()
+		return nil
+	},
+	"preview": func(t *terminal) error {
+		m := t.active()
+		if m == nil {
+			t.message = "no file found on this line"
+			return nil
+		}
+		cmd, err := t.command(m)
+		if err != nil {
+			t.report(err)
+			return nil
+		}
+		t.message = describe(cmd)
+		return nil
+	},
+	"visual": func(t *terminal) error {
+		t.visual, t.anchor = !t.visual, t.selline
+		return nil
+	},
+	"cancel": func(t *terminal) error {
+		t.visual = false
+		return nil
+	},
+	"copy":        func(t *terminal) error { t.copySelection(printLine); return nil },
+	"copytoken":   func(t *terminal) error { t.copySelection(printToken); return nil },
+	"shell":       func(t *terminal) error { t.startShell(false); return nil },
+	"shellread":   func(t *terminal) error { t.startShell(true); return nil },
+	"output":      func(t *terminal) error { t.toggleOutput(); return nil },
+	"focuspane":   func(t *terminal) error { t.focusPane(); return nil },
+	"context":     func(t *terminal) error { t.togglePreview(); return nil },
+	"help":        func(t *terminal) error { t.showHelp(); return nil },
+	"jobs":        func(t *terminal) error { t.showJobs(); return nil },
+	"history":     func(t *terminal) error { t.showHistory(); return nil },
+	"repeat":      func(t *terminal) error { t.repeatLast(); return nil },
+	"mark":        func(t *terminal) error { t.startMark(); return nil },
+	"jump":        func(t *terminal) error { t.startJump(); return nil },
+	"marks":       func(t *terminal) error { t.showMarks(); return nil },
+	"kill":        func(t *terminal) error { t.killJobs(); return nil },
+	"suspend":     func(t *terminal) error { return t.suspend() },
+	"numbers":     func(t *terminal) error { t.toggleNumbers(); t.layout(); return nil },
+	"stamps":      func(t *terminal) error { t.toggleStamps(); t.layout(); return nil },
+	"pause":       func(t *terminal) error { t.togglePause(); return nil },
+	"command":     func(t *terminal) error { t.startCommand(); return nil },
+	"rerun":       func(t *terminal) error { t.rerun(t.buffer, true); return nil },
+	"rerunappend": func(t *terminal) error { t.rerun(t.buffer, false); return nil },
+	"quit":        func(t *terminal) error { return errExit },
+	"search":      func(t *terminal) error { t.startSearch(); return nil },
+	"next":        func(t *terminal) error { t.findNext(t.selline+1, 1); return nil },
+	"prev":        func(t *terminal) error { t.findNext(t.selline-1, -1); return nil },
+	"filter":      func(t *terminal) error { t.startFilter(); return nil },
+	"prevtoken":   func(t *terminal) error { t.cycleToken(-1); return nil },
+	"nexttoken":   func(t *terminal) error { t.cycleToken(1); return nil },
+	"nexttarget":  func(t *terminal) error { t.nextTarget(1); return nil },
+	"filters":     func(t *terminal) error { t.toggleFilters(); return nil },
+	"filterlist":  func(t *terminal) error { t.showFilters(0); return nil },
+	"files":       func(t *terminal) error { t.showFiles(); return nil },
+	"dedup":       func(t *terminal) error { t.toggleDedup(); return nil },
+	"nextbuffer":  func(t *terminal) error { t.cycleBuffer(1); return nil },
+	"prevbuffer":  func(t *terminal) error { t.cycleBuffer(-1); return nil },
+	"buffers":     func(t *terminal) error { t.showBuffers(); return nil },
+	"prevtarget":  func(t *terminal) error { t.nextTarget(-1); return nil },
+	"scrollleft":  func(t *terminal) error { t.scroll(-t.cols / 2); return nil },
+	"scrollright": func(t *terminal) error { t.scroll(t.cols / 2); return nil },
+	"wrap": func(t *terminal) error {
+		t.wrap = !t.wrap
+		t.gotoLine(t.selline)
+		return nil
+	},
+	"follow": func(t *terminal) error {
+		t.follow = true
+		t.gotoLine(t.view.Rows() - 1)
+		return nil
+	},
+}
+
+// keymaps are the built in key bindings. Keys are named as by keyName and
+// sequences of keys are separated by spaces.
+var keymaps = map[string]map[string]string{
+	"default": {
+		"up":     "up",
+		"down":   "down",
+		"pgup":   "pgup",
+		"pgdn":   "pgdn",
+		"home":   "top",
+		"end":    "bottom",
+		"enter":  "plumb",
+		"ctrl-q": "quit",
+		"/":      "search",
+		"n":      "next",
+		"N":      "prev",
+		"ctrl-f": "filter",
+		"left":   "prevtoken",
+		"right":  "nexttoken",
+		"tab":    "nexttoken",
+		"h":      "scrollleft",
+		"l":      "scrollright",
+		"w":      "wrap",
+		"F":      "follow",
+		"V":      "visual",
+		"p":      "preview",
+		"esc":    "cancel",
+		"y":      "copy",
+		"Y":      "copytoken",
+		"!":      "shell",
+		"&":      "shellread",
+		"o":      "output",
+		"ctrl-w": "focuspane",
+		"P":      "context",
+		"?":      "help",
+		"J":      "jobs",
+		"H":      "history",
+		".":      "repeat",
+		"m":      "mark",
+		"'":      "jump",
+		"M":      "marks",
+		"ctrl-g": "filters",
+		"|":      "filterlist",
+		"L":      "files",
+		"U":      "dedup",
+		"g t":    "nextbuffer",
+		"g T":    "prevbuffer",
+		"B":      "buffers",
+		"]":      "nexttarget",
+		"[":      "prevtarget",
+		"ctrl-c": "kill",
+		"ctrl-z": "suspend",
+		"#":      "numbers",
+		"T":      "stamps",
+		"ctrl-s": "pause",
+		":":      "command",
+		"r":      "rerun",
+		"f5":     "rerun",
+		"R":      "rerunappend",
+	},
+	// vi adds to the default key map
+	"vi": {
+		"j":      "down",
+		"k":      "up",
+		"g g":    "top",
+		"G":      "bottom",
+		"ctrl-d": "halfpgdn",
+		"ctrl-u": "halfpgup",
+		"q":      "quit",
+	},
+}
+
+// page returns the number of lines a page holds at the current size of the
+// terminal, leaving out the status bar.
+func (t *terminal) page() int {
+	_, rows := screen.Size()
+	if rows < 2 {
+		return 1
+	}
+	return rows - 1
+}
+
+// keyName returns the name of the key pressed in ev: the character itself
+// for printable keys and a name such as up or ctrl-f for the others.
+func keyName(ev event) string {
+	if ev.Ch != 0 {
+		return string(ev.Ch)
+	}
+	return ev.Key
+}
+
+// bindings returns the key bindings of keymap with the bindings of the
+// config applied on top. Binding a key to none removes it.
+func bindings(keymap string, binds map[string]string) map[string]string {
+	b := map[string]string{}
+	for k, a := range keymaps["default"] {
+		b[k] = a
+	}
+	if keymap != "default" {
+		for k, a := range keymaps[keymap] {
+			b[k] = a
+		}
+	}
+	for k, a := range binds {
+		if a == "none" {
+			delete(b, k)
+			continue
+		}
+		b[k] = a
+	}
+	return b
+}
+
+// checkBinding reports an error if keys or action are unknown.
+func checkBinding(keys, action string) error {
+	if _, ok := actions[action]; !ok && action != "none" {
+		return fmt.Errorf("unknown action %q", action)
+	}
+	for _, k := range strings.Fields(keys) {
+		if len([]rune(k)) == 1 {
+			continue
+		}
+		known := false
+		for _, name := range keyNames {
+			if name == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown key %q", k)
+		}
+	}
+	return nil
+}
+
+// repeated are the actions a count repeats.
+var repeated = map[string]bool{
+	"up": true, "down": true, "pgup": true, "pgdn": true,
+	"halfpgup": true, "halfpgdn": true, "next": true, "prev": true,
+	"prevtoken": true, "nexttoken": true, "scrollleft": true, "scrollright": true,
+	"nexttarget": true, "prevtarget": true,
+}
+
+// countKey handles the digits of a count typed before a key and % after
+// one, which goes to that percentage of the input. It reports whether ev
+// was used.
+func (t *terminal) countKey(ev event) bool {
+	switch {
+	case ev.Ch >= '1' && ev.Ch <= '9', ev.Ch == '0' && t.count > 0:
+		t.count = t.count*10 + int(ev.Ch-'0')
+		t.message = strconv.Itoa(t.count)
+		return true
+	case ev.Ch == '%' && t.count > 0:
+		if t.count > 100 {
+			t.count = 100
+		}
+		t.gotoLine((t.view.Rows() - 1) * t.count / 100)
+		t.count = 0
+		return true
+	}
+	return false
+}
+
+// repeat runs action count times if it is a movement. A count before top
+// or bottom goes to that line of the input.
+func (t *terminal) repeat(action string, count int) error {
+	switch {
+	case count > 0 && (action == "top" || action == "bottom"):
+		t.gotoLine(t.view.Find(count - 1))
+		return nil
+	case count > 1 && action == "up":
+		t.gotoLine(t.selline - count)
+		return nil
+	case count > 1 && action == "down":
+		t.gotoLine(t.selline + count)
+		return nil
+	case count > 1 && repeated[action]:
+		for i := 0; i < count; i++ {
+			if err := actions[action](t); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return actions[action](t)
+}
+
+// key runs the action bound to the key in ev. Keys that start a sequence
+// are remembered until the next key.
+func (t *terminal) key(ev event) error {
+	name := keyName(ev)
+	if name == "" {
+		return nil
+	}
+	if f := t.argKey; f != nil {
+		t.argKey = nil
+		f(name)
+		return nil
+	}
+	seq := name
+	if t.pending != "" {
+		seq = t.pending + " " + name
+	}
+	t.pending = ""
+	if _, bound := t.keys[seq]; !bound && t.countKey(ev) {
+		return nil
+	}
+	count := t.count
+	t.count = 0
+	if a, ok := t.keys[seq]; ok {
+		return t.repeat(a, count)
+	}
+	for k := range t.keys {
+		if strings.HasPrefix(k, seq+" ") {
+			t.pending = seq
+			return nil
+		}
+	}
+	return nil
+}
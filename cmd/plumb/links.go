@@ -0,0 +1,133 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/satran/plumb"
+)
+
+// links finds the plumbable tokens of the lines shown, so they can be
+// marked, in the background: checking tokens for files, commands or commits
+// is too slow to hold up drawing.
+type links struct {
+	sync.Mutex
+	lines map[int]*lineLinks // by input line
+	queue chan linkScan
+}
+
+// lineLinks are the tokens found on a line of the given length, the last
+// line can still grow.
+type lineLinks struct {
+	length  int
+	done    bool
+	tokens  [][2]int // start and end offsets
+	targets []string // of the tokens, see visitKey
+}
+
+// linkScan asks for the tokens of a line.
+type linkScan struct {
+	index     int
+	line, dir string
+}
+
+// lineLinks returns the tokens of row n, or nil while they are being looked
+// for.
+func (t *terminal) lineLinks(n int) *lineLinks {
+	if !t.links.enabled() {
+		return nil
+	}
+	i := t.view.Index(n)
+	line, _ := t.view.Line(n)
+	l := &t.links
+	l.Lock()
+	defer l.Unlock()
+	if ll := l.lines[i]; ll != nil && ll.length == len(line) {
+		if !ll.done {
+			return nil
+		}
+		return ll
+	}
+	if l.queue == nil {
+		l.queue = make(chan linkScan, 256)
+		go t.scanLinks(l)
+	}
+	select {
+	case l.queue <- linkScan{i, string(line), t.dir(i)}:
+		l.lines[i] = &lineLinks{length: len(line)}
+	default:
+		// asked again on the next draw
+	}
+	return nil
+}
+
+// scanLinks looks for the tokens of the lines sent to l.
+func (t *terminal) scanLinks(l *links) {
+	defer crash()
+	for s := range l.queue {
+		ll := &lineLinks{length: len(s.line), done: true}
+		for _, m := range plumb.MatchLine(t.rules, s.line, s.dir) {
+			ll.tokens = append(ll.tokens, [2]int{m.Start, m.End})
+			ll.targets = append(ll.targets, visitKey(m))
+		}
+		l.Lock()
+		if old := l.lines[s.index]; old != nil && old.length == ll.length {
+			l.lines[s.index] = ll
+		}
+		l.Unlock()
+		t.requestDraw()
+	}
+}
+
+// enabled reports whether tokens are marked, see config.links.
+func (l *links) enabled() bool {
+	return l.lines != nil
+}
+
+// reset forgets the tokens found, for input that is read again.
+func (l *links) reset() {
+	l.Lock()
+	defer l.Unlock()
+	if l.lines != nil {
+		l.lines = map[int]*lineLinks{}
+	}
+}
+
+// count is the number of tokens of ll for the row state, -1 while they are
+// looked for.
+func (ll *lineLinks) count() int {
+	if ll == nil {
+		return -1
+	}
+	return len(ll.tokens)
+}
+
+// visitKey identifies the target of m among the ones visited.
+func visitKey(m *plumb.Match) string {
+	if f := m.Attrs["file"]; f != "" {
+		return f + ":" + m.Attrs["line"]
+	}
+	return m.Text
+}
+
+// visit records that the target of m was opened.
+func (t *terminal) visit(m *plumb.Match) {
+	if t.visited == nil {
+		t.visited = map[string]bool{}
+	}
+	t.visited[visitKey(m)] = true
+}
+
+// isVisited reports whether a target of row n was opened. Lines are only
+// known to be once their tokens were found.
+func (t *terminal) isVisited(n int) bool {
+	ll := t.lineLinks(n)
+	if ll == nil {
+		return false
+	}
+	for _, k := range ll.targets {
+		if t.visited[k] {
+			return true
+		}
+	}
+	return false
+}
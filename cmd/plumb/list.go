@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/satran/plumb"
+)
+
+// list writes the targets of the input to w as it is read, as JSON objects
+// one per line, instead of showing it: plumb -list.
+func (t *terminal) list(r io.Reader, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	in := bufio.NewReader(t.decode(r))
+	n := 0
+	for {
+		b, err := in.ReadBytes('\n')
+		if len(b) > 0 {
+			t.stdin.Write(b)
+		}
+		// the last line is complete once the input ends
+		for rows := t.stdin.Rows(); n < rows-1 || err != nil && n < rows; n++ {
+			line, _ := t.stdin.Line(n)
+			if !t.shown(line) {
+				continue
+			}
+			for _, m := range plumb.MatchLine(t.rules, string(line), t.dir(n)) {
+				e := newExported(m, string(line))
+				e.InputLine = n + 1
+				if err := enc.Encode(e); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
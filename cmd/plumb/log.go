@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/syslog"
+	"os"
+	"strings"
+)
+
+// The log levels, each logs what the ones before it do.
+const (
+	levelError = iota
+	levelInfo  // commands run
+	levelDebug // input, messages and events plumb handles
+	levelTrace // every decision of the rules
+)
+
+var levelNames = []string{"error", "info", "debug", "trace"}
+
+var (
+	logger   *log.Logger // nil when not logging
+	logLevel int
+)
+
+// openLog logs at level to target: stderr, syslog or a file that is
+// appended to.
+func openLog(target, level string) error {
+	logLevel = -1
+	for i, name := range levelNames {
+		if name == level {
+			logLevel = i
+		}
+	}
+	if logLevel < 0 {
+		return fmt.Errorf("log level is one of %s, not %q", strings.Join(levelNames, ", "), level)
+	}
+	var w io.Writer
+	flags := log.LstdFlags | log.Lshortfile
+	switch target {
+	case "stderr":
+		w = os.Stderr
+	case "syslog":
+		s, err := syslog.New(syslog.LOG_USER|syslog.LOG_DEBUG, "plumb")
+		if err != nil {
+			return err
+		}
+		// syslog has the time
+		w, flags = s, log.Lshortfile
+	default:
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		w = f
+	}
+	logger = log.New(w, "", flags)
+	return nil
+}
+
+// logAt logs at level, with the file and line of the caller of the function
+// calling it.
+func logAt(level int, format string, v ...interface{}) {
+	if logger == nil || level > logLevel {
+		return
+	}
+	logger.Output(3, levelNames[level]+": "+fmt.Sprintf(format, v...))
+}
+
+func logError(format string, v ...interface{}) { logAt(levelError, format, v...) }
+func logInfo(format string, v ...interface{})  { logAt(levelInfo, format, v...) }
+func debug(format string, v ...interface{})    { logAt(levelDebug, format, v...) }
+func trace(format string, v ...interface{})    { logAt(levelTrace, format, v...) }
@@ -0,0 +1,1067 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/satran/plumb"
+	"golang.org/x/text/encoding"
+)
+
+func main() {
+	var filters []*lineFilter
+	flag.Var(filterFlag{&filters, false}, "g", "show only the lines matching the regular expression `pattern`, can be given more than once")
+	flag.Var(filterFlag{&filters, true}, "v", "hide the lines matching the regular expression `pattern`, can be given more than once")
+	d := flag.Bool("debug", false, "log at the debug level, to debug.log unless -log is given")
+	logTarget := flag.String("log", "", "log to `target`: a file, stderr or syslog")
+	level := flag.String("log-level", "", "`level` of the logs: error, info (the default), debug or trace")
+	configFile := flag.String("config", filepath.Join(plumb.ConfigDir(), "config"), "configuration `file`")
+	rulesFile := flag.String("rules", "", "plumbing rules `file`, overrides the config")
+	follow := flag.Bool("f", false, "follow the end of the input as it arrives")
+	nocolor := flag.Bool("no-color", false, "strip colors from the input")
+	printTok := flag.Bool("print", false, "print the selected target on enter and exit instead of plumbing it")
+	flag.BoolVar(printTok, "o", false, "short for -print")
+	printLn := flag.Bool("print-line", false, "print the selected line on enter and exit")
+	list := flag.Bool("list", false, "write the targets of the input to stdout as JSON objects instead of showing it")
+	notifyPattern := flag.String("notify", "", "ring the bell and notify the desktop when a line matching `pattern` arrives while plumb is in the background")
+	stamps := flag.String("timestamps", stampsOff, "show when lines arrived: off, `absolute` or relative to the start")
+	export := flag.String("export", "", "write the targets to `file` on exit, - for stdout, see -export-format")
+	exportFormat := flag.String("export-format", exportQuickfix, "`format` of -export and :export, quickfix for vim -q or json")
+	dryrun := flag.Bool("dry-run", false, "show the commands plumbing would run instead of running them")
+	daemon := flag.Bool("daemon", false, "plumb messages sent to a unix socket instead of reading the input")
+	plumber := flag.Bool("9p", false, "send targets to the plan9port plumber, with -daemon read its edit port")
+	maxLines := flag.Int("max-lines", 0, "keep only the last `n` lines of the input")
+	maxBytes := flag.Int("max-bytes", 0, "keep about `n` bytes of the input in memory, at least 1048576, dropping the oldest lines a megabyte at a time")
+	mergeStderr := flag.Bool("merge-stderr", false, "interleave the stderr of the command after -- with its output instead of showing it in the output pane")
+	spill := flag.Bool("spill", false, "with -max-bytes, move the oldest lines to a temporary file instead of dropping them")
+	quitEmpty := flag.Bool("quit-on-eof-if-empty", false, "exit without showing anything when the input ends empty")
+	readArgs := flag.Bool("r", false, "read the arguments as input files instead of plumbing them")
+	cr := flag.String("cr", "collapse", "collapse lines rewritten after a carriage return to their last text, or strip carriage returns")
+	encodingName := flag.String("encoding", "", "convert the input from the encoding `name`, such as latin1 or utf-16")
+	tabstop := flag.Int("tabstop", 0, "put tab stops every `n` columns, overrides the config")
+	var watch watchList
+	flag.Var(&watch, "watch", "run the command after -- again when files matching `pattern` change")
+	sendArgs := flag.Bool("send", false, "send the arguments to the daemon to be plumbed")
+	var root string
+	flag.StringVar(&root, "root", "", "resolve relative paths against `dir`")
+	flag.StringVar(&root, "C", "", "short for -root")
+	flag.Parse()
+	if *sendArgs {
+		if err := send(flag.Args()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	conf, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *rulesFile != "" {
+		conf.rules = *rulesFile
+	}
+	rules, err := plumb.LoadRules(conf.rules)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if root != "" {
+		if root, err = filepath.Abs(root); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *d && *logTarget == "" {
+		*logTarget = "debug.log"
+	}
+	if *d && *level == "" {
+		*level = "debug"
+	}
+	if *level == "" {
+		*level = "info"
+	}
+	if *logTarget != "" {
+		if err := openLog(*logTarget, *level); err != nil {
+			log.Fatal(err)
+		}
+	}
+	plumb.Debug, plumb.Trace = debug, trace
+	t := &terminal{
+		editor:      conf.editor,
+		tabwidth:    conf.tabwidth,
+		tmux:        conf.tmux,
+		detach:      conf.detach,
+		split:       conf.split,
+		server:      conf.server,
+		syntax:      conf.syntax,
+		highlight:   conf.highlight,
+		numbers:     conf.numbers,
+		stamps:      *stamps,
+		started:     time.Now(),
+		colors:      conf.colors,
+		openers:     conf.openers,
+		keys:        bindings(conf.keymap, conf.binds),
+		rules:       rules,
+		buffer:      &buffer{name: "stdin", follow: *follow},
+		root:        root,
+		dryrun:      *dryrun,
+		quitEmpty:   *quitEmpty,
+		mergeStderr: *mergeStderr,
+		plumber:     *plumber,
+	}
+	t.buffers = []*buffer{t.buffer}
+	if conf.links {
+		t.links.lines = map[int]*lineLinks{}
+	}
+	if *encodingName != "" {
+		t.encoding, err = inputEncoding(*encodingName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *tabstop > 0 {
+		t.tabwidth = *tabstop
+	}
+	if *printTok {
+		t.print = printToken
+	}
+	if *printLn {
+		t.print = printLine
+	}
+	if *exportFormat != exportQuickfix && *exportFormat != exportJSON {
+		log.Fatalf("-export-format is %s or %s, not %q", exportQuickfix, exportJSON, *exportFormat)
+	}
+	t.exportFile, t.exportFormat = *export, *exportFormat
+	if *daemon && t.plumber {
+		log.Fatal(t.servePlumber("edit"))
+	}
+	if *daemon {
+		log.Fatal(t.serve())
+	}
+	// plumb -- cmd runs cmd instead of plumbing arguments
+	wrap := flag.NArg() > 0 && os.Args[len(os.Args)-flag.NArg()-1] == "--"
+	if len(watch) > 0 && !wrap {
+		log.Fatal("-watch needs a command to run after --")
+	}
+	if flag.NArg() > 0 && !*readArgs && !wrap {
+		if err := t.plumbArgs(flag.Args(), t.root); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if t.stamps != stampsOff && t.stamps != stampsAbsolute && t.stamps != stampsRelative {
+		log.Fatalf("-timestamps is %s, %s or %s, not %q", stampsOff, stampsAbsolute, stampsRelative, t.stamps)
+	}
+	if *cr != "collapse" && *cr != "strip" {
+		log.Fatalf("-cr is collapse or strip, not %q", *cr)
+	}
+	t.stdin = &lineReader{nocolor: *nocolor, stripCR: *cr == "strip"}
+	if *notifyPattern != "" {
+		re, err := regexp.Compile(*notifyPattern)
+		if err != nil {
+			log.Fatal(err)
+		}
+		t.notify = &notifier{re: re}
+		t.stdin.onLine = t.notifyLine
+	}
+	if *maxBytes > 0 && *maxBytes < chunkSize {
+		log.Fatalf("-max-bytes is at least %d, the input is dropped a chunk of that size at a time", chunkSize)
+	}
+	t.stdin.lines.maxLines, t.stdin.lines.maxBytes = *maxLines, *maxBytes
+	if *spill {
+		f, err := os.CreateTemp("", "plumb-spill")
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Remove(f.Name())
+		defer f.Close()
+		t.stdin.lines.spill = f
+	}
+	t.filters = filters
+	if *list {
+		if err := t.list(os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	screen, err = newTcellDisplay()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer screen.Close()
+	defer crash()
+	t.catchSuspend()
+	t.catchSignals()
+
+	fatal := func(err error) {
+		screen.Close()
+		log.Fatal(err)
+	}
+
+	cols, rows := screen.Size()
+	t.rows, t.cols = rows-1, cols
+	t.view = &view{src: t.stdin}
+	if len(t.filters) > 0 {
+		t.narrow(nil)
+	}
+	t.cmdout = &lineReader{nocolor: true}
+	switch {
+	case *readArgs:
+		t.name = strings.Join(flag.Args(), " ")
+		go t.readFiles(t.stdin, flag.Args())
+	case wrap:
+		t.name = strings.Join(flag.Args(), " ")
+		if err := t.startChild(t.buffer, flag.Args()); err != nil {
+			fatal(err)
+		}
+		if len(watch) > 0 {
+			if err := t.watch(watch); err != nil {
+				fatal(err)
+			}
+		}
+	default:
+		if name, tty := producer(); tty {
+			t.message = fmt.Sprintf("the errors of %s go to the terminal, run %s 2>&1 | plumb or plumb -merge-stderr -- %s", name, name, name)
+		}
+		go t.read(t.stdin, os.Stdin)
+	}
+	for {
+		if err := t.keypress(); err != nil {
+			if serr, ok := err.(signalError); ok {
+				screen.Close()
+				for _, b := range t.buffers {
+					if b.child != nil {
+						b.child.kill()
+					}
+				}
+				os.Exit(serr.exitCode())
+			}
+			if err != errExit {
+				fatal(err)
+			}
+			screen.Close()
+			if t.exportFile != "" {
+				if _, err := t.export(t.exportFile, t.exportFormat); err != nil {
+					log.Fatal(err)
+				}
+			}
+			for _, s := range t.output {
+				fmt.Println(s)
+			}
+			for _, b := range t.buffers[1:] {
+				if b.child != nil {
+					b.child.kill()
+				}
+			}
+			if c := t.buffers[0].child; c != nil {
+				t.buffers[0].stdin.gate.resume()
+				os.Exit(c.exitCode())
+			}
+			return
+		}
+	}
+}
+
+// lineReader keeps the lines written to it. Readers share the lock, so
+// drawing the screen only holds off the writer while a line is looked up,
+// and the writer handles a whole write at once rather than byte by byte.
+type lineReader struct {
+	sync.RWMutex
+	lines    lineStore
+	spans    map[int][]span    // styles set by escape sequences per line
+	esc      []byte            // escape sequence being read
+	cur      style             // style set by the last escape sequence
+	nocolor  bool              // strip escape sequences without keeping styles
+	stripCR  bool              // drop carriage returns instead of collapsing, see collapse
+	cr       bool              // a carriage return is pending
+	onLine   func(line []byte) // called with every line completed, if set
+	gate     gate              // pauses the reading, see copyInput
+	dirStack []string          // directories entered by make
+	dirs     []dirChange
+	size     int64 // bytes written
+	ended    bool  // the writer is done, see end
+	err      error // error the input ended with
+}
+
+func (l *lineReader) Write(p []byte) (int, error) {
+	l.Lock()
+	defer l.Unlock()
+	return l.write(p)
+}
+
+// write is Write with l locked.
+func (l *lineReader) write(p []byte) (int, error) {
+	if l.lines.len() == 0 {
+		l.lines.newLine()
+	}
+	if l.spans == nil {
+		l.spans = map[int][]span{}
+	}
+	n := len(p)
+	l.size += int64(n)
+	for len(p) > 0 {
+		if len(l.esc) > 0 {
+			l.escape(p[0])
+			p = p[1:]
+			continue
+		}
+		if l.cr {
+			l.cr = false
+			if p[0] != '\n' && !l.stripCR {
+				l.collapse()
+			}
+		}
+		i := bytes.IndexAny(p, "\n\r\x1b")
+		if i < 0 {
+			l.lines.add(p)
+			break
+		}
+		l.lines.add(p[:i])
+		switch p[i] {
+		case '\r':
+			l.cr = true
+		case '\n':
+			l.trackDir(l.lines.len() - 1)
+			if l.onLine != nil {
+				l.onLine(l.lines.line(l.lines.len() - 1))
+			}
+			first := l.lines.first
+			l.lines.newLine()
+			for n := first; n < l.lines.first; n++ {
+				delete(l.spans, n)
+			}
+			l.mark()
+		default:
+			l.escape(p[i])
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+func (l *lineReader) Line(i int) ([]byte, error) {
+	l.RLock()
+	defer l.RUnlock()
+	if i < l.lines.first || i >= l.lines.len() {
+		return nil, errors.New("line not found")
+	}
+	return l.lines.line(i), nil
+}
+
+// Rows returns the number of lines read, including the dropped ones.
+func (l *lineReader) Rows() int {
+	l.RLock()
+	defer l.RUnlock()
+	return l.lines.len()
+}
+
+// collapse empties the last line for the text after a carriage return, as
+// a terminal overwrites the line, so progress bars take up one line.
+func (l *lineReader) collapse() {
+	l.lines.truncate()
+	delete(l.spans, l.lines.len()-1)
+	l.mark()
+}
+
+// Revision returns a number that changes whenever line i is rewritten after
+// a carriage return, which can leave it as long as it was. Only the last
+// line can be.
+func (l *lineReader) Revision(i int) int {
+	l.RLock()
+	defer l.RUnlock()
+	if i != l.lines.len()-1 {
+		return 0
+	}
+	return l.lines.rewrites
+}
+
+// end records that the input ended, with err if reading it failed.
+func (l *lineReader) end(err error) {
+	l.Lock()
+	defer l.Unlock()
+	l.ended, l.err = true, err
+}
+
+// resume clears the end of the input for more to be written.
+func (l *lineReader) resume() {
+	l.Lock()
+	defer l.Unlock()
+	l.ended, l.err = false, nil
+}
+
+// reset drops every line read, keeping the settings.
+func (l *lineReader) reset() {
+	l.Lock()
+	defer l.Unlock()
+	if l.lines.spill != nil {
+		l.lines.spill.Truncate(0)
+	}
+	l.lines = lineStore{maxLines: l.lines.maxLines, maxBytes: l.lines.maxBytes, spill: l.lines.spill}
+	l.spans, l.esc, l.cur = nil, nil, style{}
+	l.dirStack, l.dirs = nil, nil
+	l.size, l.ended, l.err = 0, false, nil
+}
+
+// state returns the lines and bytes read so far and whether the input ended.
+func (l *lineReader) state() (lines int, size int64, ended bool, err error) {
+	l.RLock()
+	defer l.RUnlock()
+	lines = l.lines.len()
+	if lines > 0 && len(l.lines.line(lines-1)) == 0 {
+		lines-- // nothing after the last newline
+	}
+	return lines, l.size, l.ended, l.err
+}
+
+// Arrived returns when line i started to arrive, or the zero time.
+func (l *lineReader) Arrived(i int) time.Time {
+	l.RLock()
+	defer l.RUnlock()
+	return l.lines.arrived(i)
+}
+
+// bounds returns the first line kept and the number of lines read, leaving
+// out the last one until it is complete or the input ended.
+func (l *lineReader) bounds() (first, rows int) {
+	l.RLock()
+	defer l.RUnlock()
+	rows = l.lines.len()
+	if rows > 0 && !l.ended {
+		rows--
+	}
+	return l.lines.first, rows
+}
+
+type terminal struct {
+	*buffer      // shown, one of buffers
+	buffers      []*buffer
+	cx, cy       int
+	rows, cols   int               // rows and cols available for lines of the input
+	pane         *pane             // open pane, if any
+	cmdout       *lineReader       // output of the commands run
+	mergeStderr  bool              // see startChild
+	encoding     encoding.Encoding // of the input, nil for UTF-8
+	tty          *bufio.Reader
+	editor       string
+	tabwidth     int
+	colors       map[string]style
+	openers      map[string]string // file type to command, see config.openers
+	rules        []*plumb.Rule
+	root         string            // directory relative paths are resolved against
+	prompt       *prompt           // open prompt, if any
+	menu         *menu             // open menu, if any
+	help         *help             // open help, if any
+	search       *regexp.Regexp    // last search pattern
+	wrap         bool              // wrap long lines instead of scrolling
+	keys         map[string]string // key names to actions
+	pending      string            // keys typed of an unfinished sequence
+	count        int               // count typed before a key, see repeat
+	redraws      redraws
+	jobs         jobs             // actions run in the background
+	visited      map[string]bool  // targets opened, see visitKey
+	last         *plumb.Match     // plumbed last, see repeatLast
+	argKey       func(key string) // takes the next key, see startMark
+	drawn        []rowState       // state of the rows last drawn, see dirty
+	overlaid     bool             // a menu or the help was drawn over the rows
+	click        click            // last mouse click
+	message      string           // shown in the status bar until the next key
+	failed       bool             // message is an error
+	print        string           // print mode, see printToken
+	output       []string         // written to stdout on exit
+	exportFile   string           // the targets are written to on exit, see export
+	exportFormat string
+	quit         bool      // exit after the current event
+	quitEmpty    bool      // exit when the input ends without a byte
+	dryrun       bool      // show commands instead of running them
+	plumber      bool      // send targets to the plan9port plumber
+	tmux         string    // see inTmux
+	detach       bool      // see detached
+	split        bool      // see splitOutput
+	server       string    // see remote
+	syntax       string    // chroma style highlighting files, or off
+	highlight    bool      // highlight the input in its language
+	numbers      string    // line number mode, see numbersAbsolute
+	stamps       string    // time stamp mode, see stampsAbsolute
+	notify       *notifier // of -notify, if given
+	started      time.Time // relative time stamps count from
+	gutter       int       // columns taken by time stamps and line numbers
+}
+
+// read adds stdin to the input until it ends.
+func (t *terminal) read(in *lineReader, stdin io.Reader) {
+	defer crash()
+	err := t.copyInput(in, &binaryGuard{Reader: t.decode(stdin), t: t})
+	in.end(err)
+	t.requestDraw()
+}
+
+// copyInput adds r to the input until it ends, asking for a redraw after
+// every read.
+func (t *terminal) copyInput(in *lineReader, r io.Reader) error {
+	buf := make([]byte, 64<<10)
+	for {
+		in.gate.wait()
+		n, err := r.Read(buf)
+		if n > 0 {
+			in.Write(buf[:n])
+			t.requestDraw()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (t *terminal) draw() error {
+	cols, rows := screen.Size()
+	screen.HideCursor()
+	token := t.active()
+	h := t.paneHeight(rows)
+	t.gutter = t.gutterWidth()
+	t.cols = cols - t.gutter
+	if t.overlaid {
+		t.invalidate()
+	}
+	t.overlaid = t.menu != nil || t.help != nil
+	y := 0
+	for n := t.topline; y < rows-1-h; n++ {
+		for part := 0; part < t.height(n) && y < rows-1-h; part++ {
+			left := t.left
+			if t.wrap {
+				left = part * t.cols
+			}
+			if !t.dirty(y, t.rowState(n, part, left, token)) {
+				y++
+				continue
+			}
+			if part == 0 {
+				t.drawNumber(y, n)
+			} else {
+				t.drawNumber(y, -1)
+			}
+			t.drawLine(y, n, left, t.cols, token)
+			y++
+		}
+	}
+	screen.SetCursor(t.cx, t.cy)
+	if t.pane != nil && t.pane.preview {
+		t.preview(token, h-1)
+	}
+	if h > 0 {
+		t.drawPane(rows-1-h, h, cols)
+	}
+	if t.menu != nil {
+		t.menu.draw(cols, rows)
+	}
+	if t.help != nil {
+		t.help.draw(cols, rows)
+	}
+	if t.prompt != nil {
+		t.prompt.draw(rows-1, cols)
+	} else {
+		t.drawStatus(rows-1, cols)
+	}
+	return screen.Flush()
+}
+
+// drawLine draws line n on row y starting at column left. Arrows at the
+// edges mark text that is scrolled out of view.
+func (t *terminal) drawLine(y, n, left, cols int, token *plumb.Match) {
+	line, _ := t.view.Line(n)
+	var found [][]int
+	if t.search != nil {
+		found = t.search.FindAllIndex(line, -1)
+	}
+	spans := t.view.Spans(n)
+	if spans == nil && t.highlight {
+		spans = t.syntaxSpans(line)
+	}
+	var links [][2]int
+	if ll := t.lineLinks(n); ll != nil {
+		links = ll.tokens
+	}
+	link := t.colors["link"]
+	visited := t.isVisited(n)
+	var cur, fill style
+	selected := n == t.selline || t.inVisual(n)
+	if selected {
+		fill = t.colors["selection"]
+	}
+	set := func(x int, r rune, fg, bg attr) {
+		if x -= left; x >= 0 && x < cols {
+			screen.SetCell(t.gutter+x, y, r, fg, bg)
+		}
+	}
+	x := 0
+	for i, r := range string(line) {
+		for len(spans) > 0 && spans[0].start <= i {
+			cur, spans = spans[0].style, spans[1:]
+		}
+		fg, bg := cur.fg, cur.bg
+		if visited && fg&colorMask == colorDefault {
+			fg |= t.colors["visited"].fg
+		}
+		if selected {
+			fg, bg = fill.fg, fill.bg
+		}
+		for len(links) > 0 && links[0][1] <= i {
+			links = links[1:]
+		}
+		if len(links) > 0 && links[0][0] <= i {
+			// the link's attributes over the colors, unless it has colors
+			if link.fg&colorMask == colorDefault {
+				fg |= link.fg & attrs
+			} else {
+				fg = link.fg
+			}
+			if link.bg&colorMask != colorDefault {
+				bg = link.bg
+			}
+		}
+		for len(found) > 0 && found[0][1] <= i {
+			found = found[1:]
+		}
+		if len(found) > 0 && found[0][0] <= i {
+			fg, bg = t.colors["search"].fg, t.colors["search"].bg
+		}
+		if token != nil && n == t.sel.row && token.Start <= i && i < token.End {
+			fg, bg = t.colors["token"].fg, t.colors["token"].bg
+		}
+		w := t.runeWidth(r, x)
+		switch g := glyph(r); {
+		case r == '\t':
+			for i := 0; i < w; i++ {
+				set(x+i, ' ', fg, bg)
+			}
+		case g != "":
+			for i, c := range g {
+				set(x+i, c, fg, bg)
+			}
+		case w == 2 && (x+1 == left || x+1 == left+cols):
+			// cut in half by the edge of the screen
+			set(x, ' ', fg, bg)
+			set(x+1, ' ', fg, bg)
+		case w > 0:
+			set(x, r, fg, bg)
+		}
+		x += w
+	}
+	for ; x < left+cols; x++ {
+		set(x, ' ', fill.fg, fill.bg)
+	}
+	if c := t.view.Count(n); c > 1 {
+		t.drawCount(y, c, t.width(line)-left, cols)
+	}
+	if t.wrap {
+		return
+	}
+	st := t.colors["status"]
+	if left > 0 && len(line) > 0 {
+		screen.SetCell(t.gutter, y, '<', st.fg, st.bg)
+	}
+	if t.width(line) > left+cols {
+		screen.SetCell(t.gutter+cols-1, y, '>', st.fg, st.bg)
+	}
+}
+
+// width returns the number of columns line takes up.
+func (t *terminal) width(line []byte) int {
+	return t.column(line, len(line))
+}
+
+// column returns the column the byte at offset off of line is drawn at.
+func (t *terminal) column(line []byte, off int) int {
+	x := 0
+	for i, r := range string(line) {
+		if i >= off {
+			break
+		}
+		x += t.runeWidth(r, x)
+	}
+	return x
+}
+
+// runeWidth returns the columns r takes when drawn at column x: tabs go to
+// the next tab stop every tabwidth columns, control characters take the
+// width of their glyph, wide characters two and combining marks none.
+func (t *terminal) runeWidth(r rune, x int) int {
+	if r == '\t' {
+		return t.tabwidth - x%t.tabwidth
+	}
+	if g := glyph(r); g != "" {
+		return len(g)
+	}
+	return runewidth.RuneWidth(r)
+}
+
+// scroll scrolls the lines n columns to the right, negative n scrolls left.
+func (t *terminal) scroll(n int) {
+	t.left += n
+	if t.left < 0 {
+		t.left = 0
+	}
+}
+
+var errExit = errors.New("clean exit")
+
+func (t *terminal) keypress() error {
+	ev := screen.PollEvent()
+	if err := t.event(ev); err != nil {
+		return err
+	}
+	if t.quit {
+		return errExit
+	}
+	return nil
+}
+
+// event handles an input event.
+func (t *terminal) event(ev event) error {
+	if n := t.view.Shift(); n > 0 {
+		t.shift(n)
+	}
+	if ev.Type == eventMouse && t.prompt == nil && t.menu == nil && t.help == nil {
+		t.mouse(ev)
+		t.pauseFollow()
+		t.moved()
+		return t.draw()
+	}
+	if ev.Type == eventFocus {
+		t.redraws.Lock()
+		t.redraws.unfocused = !ev.Focused
+		t.redraws.Unlock()
+		return nil
+	}
+	if ev.Type == eventInterrupt {
+		return t.interrupted()
+	}
+	if ev.Type == eventResize {
+		t.resize()
+		return t.draw()
+	}
+	if ev.Type == eventPaste && t.prompt != nil {
+		t.prompt.paste(ev.Text)
+		return t.draw()
+	}
+	if ev.Type != eventKey {
+		return nil
+	}
+	t.message, t.failed = "", false
+	if t.help != nil {
+		_, rows := screen.Size()
+		if t.help.key(ev, t.help.height(rows)) {
+			t.help = nil
+		}
+		return t.draw()
+	}
+	if t.menu != nil {
+		m := t.menu
+		closed, err := m.key(ev)
+		if closed && t.menu == m {
+			t.menu = nil
+		}
+		if err != nil {
+			return err
+		}
+		return t.draw()
+	}
+	if t.prompt != nil {
+		p := t.prompt
+		closed, err := p.key(ev)
+		if closed && t.prompt == p {
+			t.prompt = nil
+		}
+		if err != nil {
+			return err
+		}
+		return t.draw()
+	}
+	if t.pane != nil && t.pane.focused {
+		_, rows := screen.Size()
+		if t.pane.key(ev, t.paneHeight(rows)) {
+			t.pane.focused = false
+		}
+		return t.draw()
+	}
+	if err := t.key(ev); err != nil {
+		return err
+	}
+	t.pauseFollow()
+	t.moved()
+	return t.draw()
+}
+
+// pauseFollow stops following the input once the selection has moved away
+// from the end, until F is pressed.
+func (t *terminal) pauseFollow() {
+	if t.follow && t.selline < t.view.Rows()-1 {
+		t.follow = false
+	}
+}
+
+// exec plumbs the active token of the selected line. When the line has
+// several tokens and none was chosen a menu asks which one to plumb.
+// Failures are reported in the status bar.
+func (t *terminal) exec() {
+	if t.print != "" {
+		t.printExit()
+		return
+	}
+	s := t.matches()
+	if len(s.matches) == 0 {
+		line, _ := t.view.Line(t.selline)
+		t.message = plumb.Explain(t.rules, string(line), t.dir(t.view.Index(t.selline)))
+		return
+	}
+	if len(s.matches) > 1 && !s.chosen {
+		items := make([]string, len(s.matches))
+		for i, m := range s.matches {
+			items[i] = m.Text
+		}
+		t.menu = &menu{
+			title: "plumb",
+			items: items,
+			pick: func(i int) error {
+				s.active, s.chosen = i, true
+				if err := t.plumb(s.matches[i]); err != nil {
+					t.report(err)
+				}
+				return nil
+			},
+		}
+		return
+	}
+	if err := t.plumb(s.matches[s.active]); err != nil {
+		t.report(err)
+	}
+}
+
+// report shows err in the status bar.
+func (t *terminal) report(err error) {
+	logError("%v", err)
+	t.message = err.Error()
+	t.failed = true
+}
+
+// plumb runs the action of the rule that produced m. Editors and commands
+// started for the term port are given the terminal, the output of other
+// commands goes to the output pane. In dry run mode the command is only
+// shown.
+func (t *terminal) plumb(m *plumb.Match) error {
+	cmd, err := t.command(m)
+	if err != nil {
+		return err
+	}
+	if t.dryrun {
+		t.message = describe(cmd)
+		return nil
+	}
+	logInfo("run: %s %#v", cmd.Path, cmd.Args)
+	t.visit(m)
+	t.record(m)
+	t.last = m
+	if m.Rule.To == "term" && t.split {
+		return t.splitOutput(cmd)
+	}
+	if m.Rule.To == "edit" && m.Rule.Start == nil && !t.plumber || m.Rule.To == "term" {
+		return t.run(cmd)
+	}
+	return t.capture(cmd)
+}
+
+// repeatLast plumbs the target plumbed last again, to open it once more
+// after the editor exited.
+func (t *terminal) repeatLast() {
+	if t.last == nil {
+		t.message = "nothing was plumbed yet"
+		return
+	}
+	if err := t.plumb(t.last); err != nil {
+		t.report(err)
+	}
+}
+
+// command returns the command running the action of the rule that
+// produced m.
+func (t *terminal) command(m *plumb.Match) (*exec.Cmd, error) {
+	if t.plumber && m.Rule.Start == nil {
+		return toPlumber(m), nil
+	}
+	if m.Rule.To == "edit" && m.Rule.Start == nil {
+		if opener, ok := t.opener(m.File()); ok {
+			if !strings.Contains(opener, "{file}") {
+				args := plumb.EditorArgs(opener, m.File(), 0, 0)
+				return withMatch(exec.Command(args[0], args[1:]...), m), nil
+			}
+			return plumb.Command(m, opener)
+		}
+		if t.editor == "" || !plumb.IsText(m.File()) {
+			// handed to the platform's opener
+			return plumb.Command(m, t.editor)
+		}
+		if cmd := t.remote(m.File(), m.Line(), m.Col()); cmd != nil {
+			return withMatch(cmd, m), nil
+		}
+		cmd, err := plumb.Command(m, t.editor)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Args = noWait(cmd.Args)
+		return t.inTmux(cmd), nil
+	}
+	return plumb.Command(m, t.editor)
+}
+
+// withMatch runs cmd in the directory of m with m in its environment, as
+// plumb.Command does.
+func withMatch(cmd *exec.Cmd, m *plumb.Match) *exec.Cmd {
+	cmd.Dir = m.Attrs["wdir"]
+	cmd.Env = append(os.Environ(), plumb.Env(m)...)
+	return cmd
+}
+
+// run runs cmd on the terminal plumb was started from and redraws the
+// screen once it exits. Detached commands are run as jobs, without the
+// terminal and without waiting for them.
+func (t *terminal) run(cmd *exec.Cmd) error {
+	if t.detached(cmd) {
+		return t.startJob(cmd, nil)
+	}
+	// the terminal, as stdin and stdout can be pipes
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		stdout, err := syscall.Dup(int(os.Stdout.Fd()))
+		if err != nil {
+			return err
+		}
+		f = os.NewFile(uintptr(stdout), "stdout")
+	}
+	defer f.Close()
+	if cmd.Stdin == nil {
+		cmd.Stdin = f
+	}
+	cmd.Stdout = f
+	cmd.Stderr = f
+	// the screen leaves the alternate screen and gives back the cursor,
+	// colors and mouse, and takes them again with a full redraw after
+	if err := screen.Suspend(); err != nil {
+		return err
+	}
+	// Ctrl-C goes to the command and to plumb, which survives it and kills
+	// the command on the second one in case it hangs.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	t.redraws.Lock()
+	t.redraws.running = true
+	t.redraws.Unlock()
+	if err = cmd.Start(); err == nil {
+		go func() {
+			defer crash()
+			n := 0
+			for range sigs {
+				if n++; n > 1 {
+					cmd.Process.Kill()
+				}
+			}
+		}()
+		err = cmd.Wait()
+	}
+	signal.Stop(sigs)
+	close(sigs)
+	t.redraws.Lock()
+	t.redraws.running = false
+	t.redraws.Unlock()
+	if serr := screen.Resume(); serr != nil {
+		return serr
+	}
+	t.invalidate()
+	if err != nil {
+		return fmt.Errorf("%s: %v", cmd.Args[0], err)
+	}
+	return nil
+}
+
+// describe formats cmd as a shell command line followed by the directory it
+// runs in.
+func describe(cmd *exec.Cmd) string {
+	var b strings.Builder
+	for i, a := range cmd.Args {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(shellQuote(a))
+	}
+	if r, ok := cmd.Stdin.(*bytes.Reader); ok {
+		data := make([]byte, r.Len())
+		r.ReadAt(data, 0)
+		b.WriteString(" <<< " + shellQuote(string(data)))
+	}
+	dir := cmd.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return b.String() + "  (in " + dir + ")"
+}
+
+func shellQuote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n'\"\\$`*?;&|<>()") {
+		return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+	}
+	return s
+}
+
+// resize updates the size of the screen and keeps the selection in view.
+func (t *terminal) resize() {
+	screen.Clear()
+	t.invalidate()
+	t.layout()
+}
+
+// gotoLine selects line n, scrolling it into view.
+func (t *terminal) gotoLine(n int) {
+	if rows := t.view.Rows(); n >= rows {
+		n = rows - 1
+	}
+	if n < 0 {
+		n = 0
+	}
+	t.selline = n
+	if n < t.topline {
+		t.topline = n
+	} else if n >= t.topline+t.rows {
+		t.topline = n - t.rows + 1
+	}
+	for t.wrap && t.topline < n && t.rowsBetween(t.topline, n+1) > t.rows {
+		t.topline++
+	}
+	t.cy = t.rowsBetween(t.topline, n)
+}
+
+func (t *terminal) moveCursor(key string) {
+	switch key {
+	case "up":
+		if t.selline > 0 {
+			t.gotoLine(t.selline - 1)
+		}
+	case "down":
+		if t.selline < t.view.Rows()-1 {
+			t.gotoLine(t.selline + 1)
+		}
+	}
+}
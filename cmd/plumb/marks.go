@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// markName returns the mark named by key, a letter, or false.
+func markName(key string) (rune, bool) {
+	if len(key) != 1 {
+		return 0, false
+	}
+	r := rune(key[0])
+	return r, r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// startMark sets the mark named by the next key on the selected line.
+func (t *terminal) startMark() {
+	t.message = "mark"
+	t.argKey = func(key string) {
+		r, ok := markName(key)
+		if !ok {
+			t.message = ""
+			return
+		}
+		if t.marks == nil {
+			t.marks = map[rune]int{}
+		}
+		t.marks[r] = t.view.Index(t.selline)
+		t.message = fmt.Sprintf("marked %c", r)
+	}
+}
+
+// startJump goes to the line of the mark named by the next key, or back to
+// the line the last jump was made from for '.
+func (t *terminal) startJump() {
+	t.message = "'"
+	t.argKey = func(key string) {
+		t.message = ""
+		r, ok := markName(key)
+		if key == "'" {
+			r, ok = '\'', true
+		}
+		if !ok {
+			return
+		}
+		n, set := t.marks[r]
+		if !set {
+			t.message = fmt.Sprintf("mark %c is not set", r)
+			return
+		}
+		t.jumpTo(n)
+	}
+}
+
+// jumpTo selects input line n, or the first shown after it, remembering
+// the line it jumped from as the mark '.
+func (t *terminal) jumpTo(n int) {
+	if t.marks == nil {
+		t.marks = map[rune]int{}
+	}
+	t.marks['\''] = t.view.Index(t.selline)
+	t.gotoLine(t.view.Find(n))
+}
+
+// showMarks opens the menu of the marks set, to jump to one.
+func (t *terminal) showMarks() {
+	var names []rune
+	for r := range t.marks {
+		names = append(names, r)
+	}
+	if len(names) == 0 {
+		t.message = "no marks set, set one with m and a letter"
+		return
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	items := make([]string, len(names))
+	for i, r := range names {
+		b, _ := t.stdin.Line(t.marks[r])
+		line := strings.TrimSpace(string(b))
+		items[i] = fmt.Sprintf("%c %6d  %s", r, t.marks[r]+1, line)
+	}
+	t.menu = &menu{
+		title: "marks",
+		items: items,
+		pick: func(i int) error {
+			t.jumpTo(t.marks[names[i]])
+			return nil
+		},
+	}
+}
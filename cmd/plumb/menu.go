@@ -0,0 +1,111 @@
+package main
+
+// menu is a list drawn over the lines from which one item is picked.
+type menu struct {
+	title string
+	items []string
+	sel   int
+	top   int               // first item shown
+	pick  func(i int) error // called with the picked item
+	back  func()            // called instead of closing on esc, if set
+}
+
+// key handles a key event while the menu is open and reports whether the
+// menu should be closed. Items can be picked by their number too.
+func (m *menu) key(ev event) (bool, error) {
+	switch {
+	case ev.Key == "up" || ev.Ch == 'k':
+		if m.sel > 0 {
+			m.sel--
+		}
+	case ev.Key == "down" || ev.Ch == 'j':
+		if m.sel < len(m.items)-1 {
+			m.sel++
+		}
+	case ev.Key == "enter":
+		return true, m.pick(m.sel)
+	case ev.Ch >= '1' && ev.Ch <= '9':
+		if i := int(ev.Ch - '1'); i < len(m.items) {
+			return true, m.pick(i)
+		}
+	case ev.Key == "esc" || ev.Ch == 'q':
+		if m.back != nil {
+			m.back()
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// draw draws the menu in a box in the middle of a screen of cols by rows.
+func (m *menu) draw(cols, rows int) {
+	w := len([]rune(m.title)) + 4
+	for _, it := range m.items {
+		if n := len([]rune(it)) + 6; n > w {
+			w = n
+		}
+	}
+	if w > cols-2 {
+		w = cols - 2
+	}
+	h := len(m.items)
+	if h > rows-4 {
+		h = rows - 4
+	}
+	if m.sel < m.top {
+		m.top = m.sel
+	} else if m.sel >= m.top+h {
+		m.top = m.sel - h + 1
+	}
+	x0, y0, text := drawBox(m.title, w, h, cols, rows)
+	st := style{colorDefault, colorDefault}
+	for i := 0; i < h; i++ {
+		n := m.top + i
+		fg, bg := st.fg, st.bg
+		if n == m.sel {
+			fg |= attrReverse
+		}
+		label := "  "
+		if n < 9 {
+			label = string(rune('1'+n)) + " "
+		}
+		for x := x0 + 1; x < x0+w-1; x++ {
+			screen.SetCell(x, y0+1+i, ' ', fg, bg)
+		}
+		text(x0+2, y0+1+i, label+m.items[n], fg, bg)
+	}
+	screen.HideCursor()
+}
+
+// drawBox draws an empty box w wide holding h rows with title in the middle
+// of a screen of cols by rows. It returns the top left corner and a function
+// writing text in the box that is cut at its right edge.
+func drawBox(title string, w, h, cols, rows int) (int, int, func(x, y int, s string, fg, bg attr)) {
+	x0, y0 := (cols-w)/2, (rows-h-2)/2
+	st := style{colorDefault, colorDefault}
+	text := func(x, y int, s string, fg, bg attr) {
+		for _, r := range s {
+			if x >= x0+w-1 {
+				break
+			}
+			screen.SetCell(x, y, r, fg, bg)
+			x++
+		}
+	}
+	for y := y0; y < y0+h+2; y++ {
+		for x := x0; x < x0+w; x++ {
+			r := ' '
+			switch {
+			case (y == y0 || y == y0+h+1) && (x == x0 || x == x0+w-1):
+				r = '+'
+			case y == y0 || y == y0+h+1:
+				r = '-'
+			case x == x0 || x == x0+w-1:
+				r = '|'
+			}
+			screen.SetCell(x, y, r, st.fg, st.bg)
+		}
+	}
+	text(x0+2, y0, " "+title+" ", st.fg|attrBold, st.bg)
+	return x0, y0, text
+}
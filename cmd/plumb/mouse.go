@@ -0,0 +1,74 @@
+package main
+
+import "time"
+
+// doubleClick is the longest time between two clicks of a double-click.
+const doubleClick = 400 * time.Millisecond
+
+type click struct {
+	x, y int
+	at   time.Time
+}
+
+// mouse handles a mouse event. A click selects the line and the token under
+// the pointer, a double-click or a middle click plumbs it.
+func (t *terminal) mouse(ev event) {
+	switch ev.Key {
+	case "wheelup":
+		for i := 0; i < 3; i++ {
+			t.moveCursor("up")
+		}
+	case "wheeldown":
+		for i := 0; i < 3; i++ {
+			t.moveCursor("down")
+		}
+	case "mouseleft", "mousemiddle":
+		if ev.MouseY < 0 || ev.MouseY >= t.rows || ev.MouseX < t.gutter {
+			// the gutter, the status bar or the output pane
+			return
+		}
+		n, left := t.lineAt(ev.MouseY)
+		if n >= t.view.Rows() {
+			return
+		}
+		t.gotoLine(n)
+		now := time.Now()
+		double := t.click.x == ev.MouseX && t.click.y == ev.MouseY && now.Sub(t.click.at) < doubleClick
+		t.click = click{ev.MouseX, ev.MouseY, now}
+		if !t.selectAt(ev.MouseX - t.gutter + left) {
+			return
+		}
+		if double || ev.Key == "mousemiddle" {
+			t.click = click{}
+			t.exec()
+		}
+	}
+}
+
+// selectAt makes the token drawn at column x of the selected line active.
+func (t *terminal) selectAt(x int) bool {
+	line, _ := t.view.Line(t.selline)
+	off := t.offsetAt(line, x)
+	s := t.matches()
+	for i, m := range s.matches {
+		if m.Start <= off && off < m.End {
+			s.active, s.chosen = i, true
+			return true
+		}
+	}
+	return false
+}
+
+// offsetAt returns the offset in line of the rune drawn at column x, laid out
+// the same way draw does.
+func (t *terminal) offsetAt(line []byte, x int) int {
+	col := 0
+	for i, r := range string(line) {
+		w := t.runeWidth(r, col)
+		if x < col+w {
+			return i
+		}
+		col += w
+	}
+	return len(line)
+}
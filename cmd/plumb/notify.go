@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// notifyEvery is the shortest time between notifications, so a burst of
+// matching lines notifies once.
+const notifyEvery = 5 * time.Second
+
+// notifier tells about lines matching a pattern that arrive while plumb is
+// in the background: its terminal lost focus or a command it ran has it.
+type notifier struct {
+	sync.Mutex
+	re   *regexp.Regexp
+	last time.Time
+}
+
+// background reports whether plumb isn't looked at. Terminals that don't
+// report focus are taken to have it.
+func (t *terminal) background() bool {
+	r := &t.redraws
+	r.Lock()
+	defer r.Unlock()
+	return r.unfocused || r.running
+}
+
+// notifyLine notifies about line if it matches while plumb is in the
+// background. The input calls it for every line completed.
+func (t *terminal) notifyLine(line []byte) {
+	n := t.notify
+	if n == nil || !n.re.Match(line) || !t.background() {
+		return
+	}
+	n.Lock()
+	defer n.Unlock()
+	if now := time.Now(); now.Sub(n.last) >= notifyEvery {
+		n.last = now
+		go notify(string(line))
+	}
+}
+
+// notify rings the terminal bell and shows text as a desktop notification,
+// with notify-send or on macOS osascript, if there is one.
+func notify(text string) {
+	defer crash()
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		tty.Write([]byte("\a"))
+		tty.Close()
+	}
+	var cmd *exec.Cmd
+	if _, err := exec.LookPath("notify-send"); err == nil {
+		cmd = exec.Command("notify-send", "plumb", text)
+	} else if _, err := exec.LookPath("osascript"); err == nil {
+		cmd = exec.Command("osascript", "-e", "display notification "+strconv.Quote(text)+` with title "plumb"`)
+	} else {
+		return
+	}
+	if err := cmd.Run(); err != nil {
+		logError("notify: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// pane shows other text below the lines of the input, such as the output of
+// the commands plumb ran.
+type pane struct {
+	title   string
+	src     source
+	tail    bool // show the last lines of src
+	top     int  // first line of src shown unless tail is set
+	mark    int  // line of src highlighted, or -1
+	preview bool // src is the file of the active token, see preview
+	focused bool // keys scroll the pane instead of moving the selection
+}
+
+// source is text shown in a pane.
+type source interface {
+	Line(i int) ([]byte, error)
+	Rows() int
+}
+
+// paneHeight returns the number of rows the open pane takes, with its title
+// bar, on a screen of rows.
+func (t *terminal) paneHeight(rows int) int {
+	if t.pane == nil {
+		return 0
+	}
+	h := rows / 3
+	if h < 2 {
+		h = 2
+	}
+	return h
+}
+
+// layout sets the rows left for the lines of the input by the status bar
+// and the pane.
+func (t *terminal) layout() {
+	cols, rows := screen.Size()
+	t.cols, t.rows = cols-t.gutterWidth(), rows-1-t.paneHeight(rows)
+	t.invalidate()
+	if t.rows < 1 {
+		t.rows = 1
+	}
+	t.gotoLine(t.selline)
+}
+
+// drawPane draws the pane from row y with h rows.
+func (t *terminal) drawPane(y, h, cols int) {
+	st := t.colors["status"]
+	title := " " + t.pane.title + " "
+	if t.pane.focused {
+		title += "- scrolling, esc goes back to the input "
+	}
+	x := 0
+	for _, r := range title {
+		if x >= cols {
+			break
+		}
+		screen.SetCell(x, y, r, st.fg, st.bg)
+		x++
+	}
+	for ; x < cols; x++ {
+		screen.SetCell(x, y, ' ', st.fg, st.bg)
+	}
+	top := t.pane.top
+	if t.pane.tail {
+		// the source ends with an empty line after the last newline
+		top = t.pane.src.Rows() - h
+		if top < 0 {
+			top = 0
+		}
+	}
+	for i := 1; i < h; i++ {
+		n := top + i - 1
+		line, _ := t.pane.src.Line(n)
+		var spans []span
+		if src, ok := t.pane.src.(interface{ Spans(int) []span }); ok {
+			spans = src.Spans(n)
+		}
+		fg, bg := colorDefault, colorDefault
+		if n == t.pane.mark {
+			fg, bg = t.colors["selection"].fg, t.colors["selection"].bg
+		}
+		x := 0
+		for j, r := range string(line) {
+			for n != t.pane.mark && len(spans) > 0 && spans[0].start <= j {
+				fg, bg, spans = spans[0].fg, spans[0].bg, spans[1:]
+			}
+			w := t.runeWidth(r, x)
+			if r == '\t' || w == 2 && x+1 == cols {
+				for j := 0; j < w && x < cols; j++ {
+					screen.SetCell(x, y+i, ' ', fg, bg)
+					x++
+				}
+				continue
+			}
+			if x >= cols {
+				break
+			}
+			if g := glyph(r); g != "" {
+				for _, c := range g {
+					if x < cols {
+						screen.SetCell(x, y+i, c, fg, bg)
+					}
+					x++
+				}
+				continue
+			}
+			if w > 0 {
+				screen.SetCell(x, y+i, r, fg, bg)
+			}
+			x += w
+		}
+		if n != t.pane.mark {
+			fg, bg = colorDefault, colorDefault
+		}
+		for ; x < cols; x++ {
+			screen.SetCell(x, y+i, ' ', fg, bg)
+		}
+	}
+}
+
+// key scrolls the focused pane, h rows high with its title bar, for the key
+// event ev and reports whether the focus goes back to the input.
+func (p *pane) key(ev event, h int) bool {
+	// the source ends with an empty line after the last newline
+	last := p.src.Rows() - h
+	if last < 0 {
+		last = 0
+	}
+	top := p.top
+	if p.tail {
+		top = last
+	}
+	switch {
+	case ev.Key == "up" || ev.Ch == 'k':
+		top--
+	case ev.Key == "down" || ev.Ch == 'j':
+		top++
+	case ev.Key == "pgup" || ev.Key == "ctrl-u":
+		top -= h - 1
+	case ev.Key == "pgdn" || ev.Key == "ctrl-d":
+		top += h - 1
+	case ev.Key == "home" || ev.Ch == 'g':
+		top = 0
+	case ev.Key == "end" || ev.Ch == 'G':
+		top = last
+	default:
+		return true
+	}
+	if top > last {
+		top = last
+	}
+	if top < 0 {
+		top = 0
+	}
+	// scrolled to the end it follows the output again
+	p.top, p.tail = top, top == last
+	return false
+}
+
+// focusPane lets the keys scroll the pane, opening the output pane when
+// none is open.
+func (t *terminal) focusPane() {
+	if t.pane == nil {
+		t.toggleOutput()
+	}
+	t.pane.focused = true
+}
+
+// toggleOutput opens or closes the pane with the output of commands.
+func (t *terminal) toggleOutput() {
+	if t.pane != nil {
+		t.pane = nil
+	} else {
+		t.pane = &pane{title: "output", src: t.cmdout, tail: true, mark: -1}
+	}
+	t.layout()
+}
+
+// capture starts cmd with its output going to the output pane, which is
+// opened once there is any.
+func (t *terminal) capture(cmd *exec.Cmd) error {
+	w := outputWriter{t}
+	cmd.Stdout, cmd.Stderr = w, w
+	fmt.Fprintf(t.cmdout, "$ %s\n", describe(cmd))
+	return t.startJob(cmd, nil)
+}
+
+// splitOutput starts cmd, a command plumbed to term such as man or git show,
+// with its output in the pane shown from its start instead of taking the
+// terminal, so the input stays visible.
+func (t *terminal) splitOutput(cmd *exec.Cmd) error {
+	// the header of cmd goes on the empty line after the last newline
+	top := t.cmdout.Rows() - 1
+	if top < 0 {
+		top = 0
+	}
+	if err := t.capture(cmd); err != nil {
+		return err
+	}
+	t.pane = &pane{title: "output", src: t.cmdout, top: top, mark: -1}
+	t.layout()
+	return nil
+}
+
+// outputWriter adds what it is written to the output of commands and shows
+// it.
+type outputWriter struct {
+	t *terminal
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	t := w.t
+	t.cmdout.Write(p)
+	t.redraws.Lock()
+	t.redraws.output = true
+	t.redraws.Unlock()
+	t.requestDraw()
+	return len(p), nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import "sync"
+
+// gate holds up reading the input while it is paused, so the pipe fills up
+// and the producer blocks on its writes instead of the input growing.
+type gate struct {
+	sync.Mutex
+	paused bool
+	open   chan struct{} // closed on resume
+}
+
+// wait returns once the input isn't paused.
+func (g *gate) wait() {
+	g.Lock()
+	paused, open := g.paused, g.open
+	g.Unlock()
+	if paused {
+		<-open
+	}
+}
+
+// toggle pauses reading or resumes it, and reports whether it is paused.
+func (g *gate) toggle() bool {
+	g.Lock()
+	defer g.Unlock()
+	if g.paused {
+		close(g.open)
+	} else {
+		g.open = make(chan struct{})
+	}
+	g.paused = !g.paused
+	return g.paused
+}
+
+// resume resumes reading if it is paused, for a reader that is to finish.
+func (g *gate) resume() {
+	if g.isPaused() {
+		g.toggle()
+	}
+}
+
+// isPaused reports whether reading is paused.
+func (g *gate) isPaused() bool {
+	g.Lock()
+	defer g.Unlock()
+	return g.paused
+}
+
+// togglePause stops reading the input of the buffer shown, or reads it
+// again.
+func (t *terminal) togglePause() {
+	if t.stdin.gate.toggle() {
+		t.message = "input paused, ctrl-s reads it again"
+	} else {
+		t.message = "input resumed"
+	}
+}
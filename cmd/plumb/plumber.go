@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/satran/plumb"
+)
+
+// message is a plan9 plumber message as described in plumb(2).
+type message struct {
+	src, dst string
+	wdir     string
+	typ      string
+	attrs    [][2]string
+	data     []byte
+}
+
+// pack formats msg in the plumber's wire format.
+func (msg *message) pack() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s\n%s\n%s\n%s\n", msg.src, msg.dst, msg.wdir, msg.typ)
+	for i, kv := range msg.attrs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		v := kv[1]
+		if v == "" || strings.ContainsAny(v, " \t'=") {
+			v = "'" + strings.Replace(v, "'", "''", -1) + "'"
+		}
+		fmt.Fprintf(&b, "%s=%s", kv[0], v)
+	}
+	fmt.Fprintf(&b, "\n%d\n", len(msg.data))
+	b.Write(msg.data)
+	return b.Bytes()
+}
+
+// unpack reads a message in the plumber's wire format.
+func unpack(r *bufio.Reader) (*message, error) {
+	var fields [6]string
+	for i := range fields {
+		s, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && i > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		fields[i] = strings.TrimSuffix(s, "\n")
+	}
+	msg := &message{src: fields[0], dst: fields[1], wdir: fields[2], typ: fields[3]}
+	attrs, err := plumb.SplitFields(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("attr: %v", err)
+	}
+	for _, a := range attrs {
+		kv := strings.SplitN(a, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("malformed attribute %q", a)
+		}
+		msg.attrs = append(msg.attrs, [2]string{kv[0], kv[1]})
+	}
+	n, err := strconv.Atoi(fields[5])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("malformed data length %q", fields[5])
+	}
+	msg.data = make([]byte, n)
+	if _, err := io.ReadFull(r, msg.data); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// toPlumber returns the command sending m to the plan9port plumber, which
+// applies its own rules to route it, to acme for example.
+func toPlumber(m *plumb.Match) *exec.Cmd {
+	wdir := m.Attrs["wdir"]
+	if wdir == "" {
+		wdir, _ = os.Getwd()
+	}
+	msg := &message{src: "plumb", wdir: wdir, typ: "text", data: []byte(m.Text)}
+	for _, kv := range m.Rule.Attrs {
+		if v := m.Attrs[kv[0]]; v != "" {
+			msg.attrs = append(msg.attrs, [2]string{kv[0], v})
+		}
+	}
+	cmd := exec.Command("9p", "write", "plumb/send")
+	cmd.Stdin = bytes.NewReader(msg.pack())
+	return cmd
+}
+
+// servePlumber plumbs the messages the plan9port plumber sends to port.
+func (t *terminal) servePlumber(port string) error {
+	cmd := exec.Command("9p", "read", "plumb/"+port)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// plumb what the plumber sends here instead of sending it back
+	t.plumber = false
+	r := bufio.NewReader(out)
+	for {
+		msg, err := unpack(r)
+		if err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			if err == io.EOF {
+				err = fmt.Errorf("plumber closed port %s", port)
+			}
+			return err
+		}
+		debug("plumber: %+v", msg)
+		wdir := msg.wdir
+		if t.root != "" {
+			wdir = t.root
+		}
+		if err := t.plumbArgs([]string{string(msg.data)}, wdir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/satran/plumb"
+)
+
+// maxPreview is the most of a file read for the preview.
+const maxPreview = 4 << 20
+
+// fileLines are the lines of a file shown in the preview.
+type fileLines struct {
+	*lineReader
+	name string
+}
+
+// readLines reads the lines of the file name, highlighted with the syntax
+// style unless it is off, or the reason it can't be shown as the only line.
+func readLines(name, syntax string) *fileLines {
+	f := &fileLines{&lineReader{nocolor: syntax == "off"}, name}
+	fail := func(err error) *fileLines {
+		f.Write([]byte(err.Error()))
+		return f
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return fail(err)
+	}
+	defer file.Close()
+	if fi, err := file.Stat(); err == nil && fi.IsDir() {
+		return fail(fmt.Errorf("%s is a directory", name))
+	}
+	b, err := io.ReadAll(io.LimitReader(file, maxPreview))
+	if err != nil {
+		return fail(err)
+	}
+	if bytes.IndexByte(b, 0) >= 0 {
+		return fail(fmt.Errorf("%s is a binary file", name))
+	}
+	if syntax != "off" {
+		b = highlight(name, b, syntax)
+	}
+	f.Write(b)
+	return f
+}
+
+// togglePreview opens or closes the preview of the file of the active
+// token.
+func (t *terminal) togglePreview() {
+	if t.pane != nil && t.pane.preview {
+		t.pane = nil
+	} else {
+		t.pane = &pane{preview: true, mark: -1}
+	}
+	t.layout()
+}
+
+// preview shows the file of m in the pane, with its line in the middle of
+// the h rows shown.
+func (t *terminal) preview(m *plumb.Match, h int) {
+	p := t.pane
+	file := ""
+	if m != nil {
+		file = m.Attrs["file"]
+	}
+	if file == "" {
+		p.title, p.src, p.mark = "no file on this line", &lineReader{}, -1
+		return
+	}
+	if f, ok := p.src.(*fileLines); !ok || f.name != file {
+		p.src = readLines(file, t.syntax)
+	}
+	line := m.Line()
+	p.title = location(file, line, 0)
+	p.mark = line - 1
+	p.top = p.mark - (h-1)/2
+	if p.top < 0 {
+		p.top = 0
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/satran/plumb"
+)
+
+// Print modes write the selection to stdout on exit instead of plumbing it.
+const (
+	printToken = "token" // the target of the active token, as file:line:col
+	printLine  = "line"  // the whole selected line
+)
+
+// selection returns what mode asks for of row, with m its active token.
+func (t *terminal) selection(mode string, row int, m *plumb.Match) (string, bool) {
+	if mode == printLine {
+		line, _ := t.view.Line(row)
+		return string(line), true
+	}
+	if m == nil {
+		return "", false
+	}
+	if file := m.Attrs["file"]; file != "" {
+		return location(file, m.Line(), m.Col()), true
+	}
+	return m.Text, true
+}
+
+// location formats file, line and col as file:line:col leaving out zeros.
+func location(file string, line, col int) string {
+	if line > 0 {
+		file += ":" + strconv.Itoa(line)
+		if col > 0 {
+			file += ":" + strconv.Itoa(col)
+		}
+	}
+	return file
+}
+
+// selected returns what mode asks for of the selected line, or of every
+// line of the visual selection.
+func (t *terminal) selected(mode string) []string {
+	lo, hi := t.selline, t.selline
+	if t.visual {
+		lo, hi = t.anchor, t.selline
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+	}
+	var out []string
+	for row := lo; row <= hi; row++ {
+		var m *plumb.Match
+		if row == t.selline && !t.visual {
+			m = t.active()
+		} else if ms, _ := t.lineMatches(row); len(ms) > 0 {
+			m = ms[0]
+		}
+		if s, ok := t.selection(mode, row, m); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// printExit queues the selection for printing and exits.
+func (t *terminal) printExit() {
+	t.output = append(t.output, t.selected(t.print)...)
+	t.quit = true
+}
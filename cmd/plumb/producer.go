@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// producer finds the command writing to the pipe on stdin in /proc and
+// reports whether its stderr still goes to the terminal, where errors it
+// prints are lost to plumb. It finds nothing on systems without /proc.
+func producer() (name string, tty bool) {
+	in, err := os.Readlink("/proc/self/fd/0")
+	if err != nil || !strings.HasPrefix(in, "pipe:") {
+		return "", false
+	}
+	self := strconv.Itoa(os.Getpid())
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/1")
+	for _, fd := range fds {
+		dir := filepath.Dir(filepath.Dir(fd))
+		if filepath.Base(dir) == self {
+			continue
+		}
+		if out, _ := os.Readlink(fd); out != in {
+			continue
+		}
+		comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+		stderr, _ := os.Readlink(filepath.Join(dir, "fd", "2"))
+		tty := strings.HasPrefix(stderr, "/dev/pts/") || strings.HasPrefix(stderr, "/dev/tty")
+		return strings.TrimSpace(string(comm)), tty
+	}
+	return "", false
+}
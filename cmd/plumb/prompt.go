@@ -0,0 +1,71 @@
+package main
+
+import "strings"
+
+// prompt reads a line of input on the last row of the screen.
+type prompt struct {
+	label  string
+	text   []rune
+	change func(text string)       // called after every edit
+	done   func(text string) error // called on enter
+	cancel func()                  // called on escape
+}
+
+// key handles a key event while the prompt is open and reports whether the
+// prompt should be closed.
+func (p *prompt) key(ev event) (bool, error) {
+	switch {
+	case ev.Key == "enter":
+		if p.done != nil {
+			return true, p.done(string(p.text))
+		}
+		return true, nil
+	case ev.Key == "esc" || ev.Key == "ctrl-g":
+		if p.cancel != nil {
+			p.cancel()
+		}
+		return true, nil
+	case ev.Key == "backspace":
+		if len(p.text) == 0 {
+			return false, nil
+		}
+		p.text = p.text[:len(p.text)-1]
+	case ev.Key == "ctrl-u":
+		p.text = nil
+	case ev.Key == "space":
+		p.text = append(p.text, ' ')
+	case ev.Ch != 0:
+		p.text = append(p.text, ev.Ch)
+	default:
+		return false, nil
+	}
+	if p.change != nil {
+		p.change(string(p.text))
+	}
+	return false, nil
+}
+
+// paste adds pasted text to the prompt, on one line.
+func (p *prompt) paste(text string) {
+	text = strings.NewReplacer("\r\n", " ", "\n", " ", "\t", " ").Replace(text)
+	p.text = append(p.text, []rune(text)...)
+	if p.change != nil {
+		p.change(string(p.text))
+	}
+}
+
+// draw draws the prompt on row y and places the cursor after the text.
+func (p *prompt) draw(y, cols int) {
+	x := 0
+	for _, r := range p.label + string(p.text) {
+		if x >= cols {
+			break
+		}
+		screen.SetCell(x, y, r, colorDefault, colorDefault)
+		x++
+	}
+	screen.SetCursor(x, y)
+	for ; x < cols; x++ {
+		screen.SetCell(x, y, ' ', colorDefault, colorDefault)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"syscall"
+	"time"
+)
+
+// frame is the shortest time between redraws for arriving input.
+const frame = time.Second / 30
+
+// redraws batches the redraws asked for by the goroutines reading input so
+// a fast producer doesn't redraw the screen for every write. The screen is
+// only drawn by the main loop, which is woken up with screen.Interrupt.
+type redraws struct {
+	sync.Mutex
+	scheduled bool
+	last      time.Time
+	output    bool           // commands wrote output
+	rerun     bool           // watched files changed, see watch
+	suspend   bool           // plumb was sent SIGTSTP, see catchSuspend
+	signal    syscall.Signal // plumb is to exit for it, see catchSignals
+	running   bool           // a command has the terminal, see run
+	unfocused bool           // the terminal lost focus, see background
+	binary    chan struct{}  // the input looks binary, see confirmBinary
+}
+
+// requestDraw asks the main loop to draw the screen within a frame.
+func (t *terminal) requestDraw() {
+	r := &t.redraws
+	r.Lock()
+	defer r.Unlock()
+	if r.scheduled {
+		return
+	}
+	r.scheduled = true
+	wait := frame - time.Since(r.last)
+	if wait < 0 {
+		wait = 0
+	}
+	time.AfterFunc(wait, screen.Interrupt)
+}
+
+// requestRerun asks the main loop to run the command again.
+func (t *terminal) requestRerun() {
+	t.redraws.Lock()
+	t.redraws.rerun = true
+	t.redraws.Unlock()
+	t.requestDraw()
+}
+
+// interrupted draws the screen for the input that arrived since the last
+// redraw.
+func (t *terminal) interrupted() error {
+	r := &t.redraws
+	r.Lock()
+	r.scheduled, r.last = false, time.Now()
+	output, rerun, binary, suspend := r.output, r.rerun, r.binary, r.suspend
+	r.output, r.rerun, r.binary, r.suspend = false, false, nil, false
+	sig := r.signal
+	r.Unlock()
+	if sig != 0 {
+		return signalError{sig}
+	}
+	if suspend {
+		if err := t.suspend(); err != nil {
+			return err
+		}
+	}
+	t.finishJobs()
+	if binary != nil {
+		t.askBinary(binary)
+	}
+	if rerun {
+		// -watch runs the command after --
+		t.rerun(t.buffers[0], true)
+	}
+	if output && (t.pane == nil || t.pane.src != t.cmdout) {
+		t.pane = &pane{title: "output", src: t.cmdout, tail: true, mark: -1}
+		t.layout()
+	}
+	if t.quitEmpty {
+		if _, size, ended, _ := t.stdin.state(); ended && size == 0 {
+			return errExit
+		}
+	}
+	if t.follow {
+		t.gotoLine(t.view.Rows() - 1)
+	}
+	return t.draw()
+}
+
+// shift moves the selection and the rows shown up by n rows, after the input
+// dropped its oldest lines, so they stay on the same lines. It is called
+// before every event so keys act on the rows last seen.
+func (t *terminal) shift(n int) {
+	up := func(row int) int {
+		if row -= n; row < 0 {
+			return 0
+		}
+		return row
+	}
+	t.selline, t.topline, t.anchor = up(t.selline), up(t.topline), up(t.anchor)
+	t.sel = nil
+	t.invalidate()
+}
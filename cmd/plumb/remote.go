@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// remote returns the command sending file to an editor that is already
+// running, or nil if there is none to send it to. nvim is reached at the
+// configured server address or $NVIM, as in its terminal, and vim or gvim
+// by the configured server name.
+func (t *terminal) remote(file string, line, col int) *exec.Cmd {
+	args := strings.Fields(t.editor)
+	if len(args) == 0 {
+		return nil
+	}
+	server := t.server
+	// the editor can be running in another directory
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	switch filepath.Base(args[0]) {
+	case "nvim":
+		if server == "" {
+			server = os.Getenv("NVIM")
+		}
+		if server == "" {
+			return nil
+		}
+		keys := `<C-\><C-N>:drop ` + strings.Replace(file, " ", `\ `, -1) + "<CR>"
+		if line > 0 {
+			if col < 1 {
+				col = 1
+			}
+			keys += fmt.Sprintf(":call cursor(%d,%d)<CR>", line, col)
+		}
+		return exec.Command(args[0], "--server", server, "--remote-send", keys)
+	case "vim", "gvim":
+		if server == "" {
+			return nil
+		}
+		args = append(args, "--servername", server, "--remote-silent")
+		if line > 0 {
+			if col < 1 {
+				col = 1
+			}
+			args = append(args, fmt.Sprintf("+call cursor(%d,%d)", line, col))
+		}
+		return exec.Command(args[0], append(args[1:], file)...)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// textRows returns the number of rows with lines, leaving out the empty one
+// after the last newline.
+func (t *terminal) textRows() int {
+	rows := t.view.Rows()
+	if last, _ := t.view.Line(rows - 1); len(last) == 0 && t.view.Index(rows-1) == t.stdin.Rows()-1 {
+		rows--
+	}
+	return rows
+}
+
+// save writes the lines shown, as filtered and sorted, to the file name.
+// An existing file is only replaced with force.
+func (t *terminal) save(name string, force bool) (int, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(name, flags, 0644)
+	if os.IsExist(err) {
+		return 0, fmt.Errorf("%s exists, :w! %s replaces it", name, name)
+	}
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	rows := t.textRows()
+	for row := 0; row < rows; row++ {
+		line, _ := t.view.Line(row)
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	err = w.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return rows, err
+}
+
+// saveCommand handles the w command of the prompt, reporting whether text is
+// one: w writes the lines shown to a file, w! replaces it if it exists.
+func (t *terminal) saveCommand(text string) (bool, error) {
+	name, arg, _ := strings.Cut(text, " ")
+	if name != "w" && name != "w!" {
+		return false, nil
+	}
+	file := strings.TrimSpace(arg)
+	if file == "" {
+		return true, fmt.Errorf("w needs a file")
+	}
+	n, err := t.save(file, name == "w!")
+	if err != nil {
+		return true, err
+	}
+	t.message = fmt.Sprintf("wrote %d lines to %s", n, file)
+	return true, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+// display is the terminal plumb draws on. Drawing goes through it so that the
+// terminal library can be swapped, see tcellDisplay.
+type display interface {
+	Size() (cols, rows int)
+	SetCell(x, y int, r rune, fg, bg attr)
+	SetCursor(x, y int)
+	HideCursor()
+	Clear()
+	Flush() error
+	// PollEvent waits for the next event, Interrupt makes it return an
+	// interrupt event from another goroutine.
+	PollEvent() event
+	Interrupt()
+	// Suspend gives the terminal back for another program, Resume takes it
+	// again and redraws it.
+	Suspend() error
+	Resume() error
+	Close()
+}
+
+// screen is the display plumb draws on once it started.
+var screen display
+
+// attr is a color with the attributes it is drawn with. Colors are either
+// the default color, one of the 256 colors of the palette or a 24 bit color,
+// see paletteColor and rgbColor.
+type attr uint32
+
+const (
+	colorDefault attr = iota
+	colorBlack
+	colorRed
+	colorGreen
+	colorYellow
+	colorBlue
+	colorMagenta
+	colorCyan
+	colorWhite
+)
+
+const (
+	colorRGB      attr = 1 << 24 // the low 24 bits are the rgb value
+	attrBold      attr = 1 << 25
+	attrUnderline attr = 1 << 26
+	attrReverse   attr = 1 << 27
+
+	colorMask = colorRGB | (colorRGB - 1)
+	attrs     = attrBold | attrUnderline | attrReverse
+)
+
+// paletteColor returns color n of the 256 color palette.
+func paletteColor(n int) attr {
+	return attr(n + 1)
+}
+
+// rgbColor returns the 24 bit color r, g, b.
+func rgbColor(r, g, b int) attr {
+	return colorRGB | attr(r&0xff)<<16 | attr(g&0xff)<<8 | attr(b&0xff)
+}
+
+type eventType int
+
+const (
+	eventKey eventType = iota
+	eventMouse
+	eventPaste
+	eventResize
+	eventInterrupt
+	eventFocus
+)
+
+// event is an input event. Keys are printable characters in Ch or named
+// in Key as by keyName, mouse buttons are named in Key too: mouseleft,
+// mousemiddle, mouseright, wheelup and wheeldown.
+type event struct {
+	Type           eventType
+	Key            string
+	Ch             rune
+	MouseX, MouseY int
+	Text           string // pasted text
+	Focused        bool   // the terminal got the focus, or lost it
+}
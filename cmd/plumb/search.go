@@ -0,0 +1,57 @@
+package main
+
+import "regexp"
+
+// compileSearch compiles the search text as a regular expression, falling
+// back to a literal match while the expression is incomplete.
+func compileSearch(text string) *regexp.Regexp {
+	if text == "" {
+		return nil
+	}
+	re, err := regexp.Compile(text)
+	if err != nil {
+		return regexp.MustCompile(regexp.QuoteMeta(text))
+	}
+	return re
+}
+
+// startSearch opens the search prompt. The selection follows the first match
+// at or after the current line as the pattern is typed.
+func (t *terminal) startSearch() {
+	from := t.selline
+	prev := t.search
+	t.prompt = &prompt{
+		label: "/",
+		change: func(text string) {
+			t.search = compileSearch(text)
+			t.gotoLine(from)
+			t.findNext(from, 1)
+		},
+		cancel: func() {
+			t.search = prev
+			t.gotoLine(from)
+		},
+	}
+}
+
+// findNext moves the selection to the next line matching the search,
+// starting at line from and going in direction dir. The search wraps around
+// the end of the buffer.
+func (t *terminal) findNext(from, dir int) bool {
+	if t.search == nil {
+		return false
+	}
+	rows := t.view.Rows()
+	for i := 0; i < rows; i++ {
+		n := ((from+i*dir)%rows + rows) % rows
+		line, err := t.view.Line(n)
+		if err != nil {
+			continue
+		}
+		if t.search.Match(line) {
+			t.gotoLine(n)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// startShell opens a prompt for a shell command in which {} stands for the
+// selected token's target, or the selected line if it has none. With read
+// the command's output is added to the buffer, otherwise it goes to the
+// output pane.
+func (t *terminal) startShell(read bool) {
+	label := "!"
+	if read {
+		label = "&"
+	}
+	t.prompt = &prompt{
+		label: label,
+		done: func(text string) error {
+			if strings.TrimSpace(text) == "" {
+				return nil
+			}
+			command := strings.Replace(text, "{}", shellQuote(t.target()), -1)
+			if t.dryrun {
+				t.message = command
+				return nil
+			}
+			var err error
+			if read {
+				err = t.readShell(command)
+			} else {
+				err = t.capture(t.shell(command))
+			}
+			if err != nil {
+				t.report(err)
+			}
+			return nil
+		},
+	}
+}
+
+// target is what {} stands for in shell commands.
+func (t *terminal) target() string {
+	if s, ok := t.selection(printToken, t.selline, t.active()); ok {
+		return s
+	}
+	s, _ := t.selection(printLine, t.selline, nil)
+	return s
+}
+
+func (t *terminal) shell(command string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = t.root
+	return cmd
+}
+
+// readShell runs command as a job and adds its output to the end of the
+// buffer once it exits.
+func (t *terminal) readShell(command string) error {
+	var buf bytes.Buffer
+	cmd := t.shell(command)
+	cmd.Stdout, cmd.Stderr = &buf, &buf
+	in := t.stdin
+	return t.startJob(cmd, func(err error) {
+		out := buf.Bytes()
+		if len(out) > 0 && out[len(out)-1] != '\n' {
+			out = append(out, '\n')
+		}
+		in.appendLines(out)
+		if err != nil {
+			t.report(fmt.Errorf("%s: %v", command, err))
+			return
+		}
+		t.message = fmt.Sprintf("read %d lines", bytes.Count(out, []byte("\n")))
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	runtimedebug "runtime/debug"
+	"syscall"
+)
+
+// signalError ends the main loop for a signal plumb was sent.
+type signalError struct {
+	sig syscall.Signal
+}
+
+func (e signalError) Error() string {
+	return e.sig.String()
+}
+
+// exitCode is the exit code of a shell for a process killed by the signal.
+func (e signalError) exitCode() int {
+	return 128 + int(e.sig)
+}
+
+// catchSignals has the main loop exit cleanly when plumb is interrupted,
+// terminated or its terminal hangs up. Interrupts while a command has the
+// terminal are for the command, see run.
+func (t *terminal) catchSignals() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		defer crash()
+		for sig := range sigs {
+			t.redraws.Lock()
+			if sig == syscall.SIGINT && t.redraws.running {
+				t.redraws.Unlock()
+				continue
+			}
+			t.redraws.signal = sig.(syscall.Signal)
+			t.redraws.Unlock()
+			t.requestDraw()
+		}
+	}()
+}
+
+// crash gives the terminal back after a panic so the error can be read,
+// and exits. Every goroutine of the interface defers it, as a panic in any
+// of them ends plumb.
+func crash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if screen != nil {
+		screen.Close()
+	}
+	fmt.Fprintf(os.Stderr, "plumb: %v\n\n%s", r, runtimedebug.Stack())
+	os.Exit(2)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Orders of the sort command.
+const (
+	sortText = "text" // the lines as text
+	sortPath = "path" // the file of their first target
+	sortLine = "line" // the file and line of their first target
+)
+
+// sortKey is what a row is sorted by.
+type sortKey struct {
+	line   int // of the input
+	text   []byte
+	file   string
+	lineno int
+}
+
+// sortRows shows the rows ordered by, keeping the input order of rows that
+// are equal. By path or line the rows without a file go last. The selection
+// stays on the same input line.
+func (t *terminal) sortRows(by string) error {
+	if by != sortText && by != sortPath && by != sortLine {
+		return fmt.Errorf("sort is by %s, %s or %s, not %q", sortText, sortPath, sortLine, by)
+	}
+	sel := t.view.Index(t.selline)
+	t.view.SetOrder(nil)
+	keys := make([]sortKey, t.textRows())
+	for row := range keys {
+		k := &keys[row]
+		k.line = t.view.Index(row)
+		k.text, _ = t.view.Line(row)
+		if by == sortText {
+			continue
+		}
+		ms, _ := t.lineMatches(row)
+		for _, m := range ms {
+			if f := m.Attrs["file"]; f != "" {
+				k.file, k.lineno = f, m.Line()
+				break
+			}
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if by == sortText {
+			return bytes.Compare(a.text, b.text) < 0
+		}
+		if (a.file == "") != (b.file == "") {
+			return b.file == ""
+		}
+		if a.file != b.file || by == sortPath {
+			return a.file < b.file
+		}
+		return a.lineno < b.lineno
+	})
+	lines := make([]int, len(keys))
+	for i, k := range keys {
+		lines[i] = k.line
+	}
+	t.view.SetOrder(lines)
+	if sel >= 0 {
+		t.gotoLine(t.view.Find(sel))
+	}
+	t.invalidate()
+	t.message = fmt.Sprintf("%d lines sorted by %s, :unsort puts them back in order", len(lines), by)
+	return nil
+}
+
+// unsortRows shows the rows in input order again.
+func (t *terminal) unsortRows() error {
+	if !t.view.Sorted() {
+		return fmt.Errorf("the lines are not sorted")
+	}
+	sel := t.view.Index(t.selline)
+	t.view.SetOrder(nil)
+	if sel >= 0 {
+		t.gotoLine(t.view.Find(sel))
+	}
+	t.invalidate()
+	return nil
+}
+
+// sortCommand handles the commands of the prompt reordering the rows,
+// reporting whether text is one: sort orders them by text, path or line and
+// unsort puts them back in input order. Lines arriving after a sort follow
+// the sorted ones.
+func (t *terminal) sortCommand(text string) (bool, error) {
+	name, arg, _ := strings.Cut(text, " ")
+	switch name {
+	case "sort":
+		if arg = strings.TrimSpace(arg); arg == "" {
+			arg = sortText
+		}
+		return true, t.sortRows(arg)
+	case "unsort":
+		return true, t.unsortRows()
+	}
+	return false, nil
+}
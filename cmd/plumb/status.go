@@ -0,0 +1,79 @@
+package main
+
+import "fmt"
+
+// newRows returns the number of rows that arrived since the user moved away
+// from the last one, while it isn't on the screen.
+func (t *terminal) newRows() int {
+	rows := t.view.Rows()
+	// seen shrinks when the input was read again or filtered
+	if !t.away || t.topline+t.rows >= rows || t.seen > rows {
+		t.away, t.seen = false, rows
+	}
+	return rows - t.seen
+}
+
+// moved notes that the user moved away from the last row if it isn't on the
+// screen after a key or click.
+func (t *terminal) moved() {
+	if !t.away && t.topline+t.rows < t.view.Rows() {
+		t.away = true
+	}
+}
+
+// drawStatus draws the status bar on row y. It shows the message if there is
+// one or else the token that would be plumbed, followed by the position in
+// the input and whether the input ended.
+func (t *terminal) drawStatus(y, cols int) {
+	st := t.colors["status"]
+	if t.failed {
+		st = t.colors["error"]
+	}
+	left := t.message
+	if left == "" {
+		if m := t.active(); m != nil {
+			left = m.Text
+		}
+	}
+	total := t.view.Rows()
+	pct := 100
+	if total > t.rows {
+		pct = t.topline * 100 / (total - t.rows)
+	}
+	right := fmt.Sprintf(" %d/%d %d%% ", t.selline+1, total, pct)
+	if t.follow {
+		right = " follow" + right
+	} else if n := t.newRows(); n > 0 {
+		right = fmt.Sprintf(" +%d new lines, F goes to them", n) + right
+	}
+	if t.stdin.gate.isPaused() {
+		right = " paused" + right
+	}
+	if len(t.buffers) > 1 {
+		name := t.name
+		if len(name) > 20 {
+			name = name[:17] + "..."
+		}
+		right = fmt.Sprintf(" [%d/%d %s]", t.bufferIndex()+1, len(t.buffers), name) + right
+	}
+	if lines, size, ended, err := t.stdin.state(); err != nil {
+		right = fmt.Sprintf(" %v", err) + right
+	} else if ended {
+		right = fmt.Sprintf(" ended, %d lines %d bytes", lines, size) + right
+	}
+	x := 0
+	for _, r := range left {
+		if x >= cols-len(right) {
+			break
+		}
+		screen.SetCell(x, y, r, st.fg, st.bg)
+		x++
+	}
+	for ; x < cols-len(right); x++ {
+		screen.SetCell(x, y, ' ', st.fg, st.bg)
+	}
+	for _, r := range right {
+		screen.SetCell(x, y, r, st.fg, st.bg)
+		x++
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// chunkSize is the size of the blocks lines are stored in.
+const chunkSize = 1 << 20
+
+// lineStore holds lines back to back in large append-only chunks and
+// indexes them by their position, so that reading a line doesn't allocate
+// and huge inputs take little more memory than their size. The zero value
+// holds no lines.
+//
+// With limits set the oldest lines are dropped, or their chunks are moved to
+// the spill file, so a store can be fed an endless stream. Lines keep their
+// numbers when older ones are dropped.
+type lineStore struct {
+	chunks   [][]byte // nil once dropped or spilled
+	index    []lineRef
+	first    int // lines dropped from the front
+	low      int // first chunk in memory
+	size     int // bytes of the chunks in memory
+	maxLines int // lines kept, 0 for no limit
+	maxBytes int // bytes kept in memory, 0 for no limit
+	spill    *os.File
+	spilled  []int64 // offset of every spilled chunk in spill
+	rewrites int     // times the last line was emptied, see truncate
+}
+
+// lineRef is where a line is stored and when its first byte arrived.
+type lineRef struct {
+	chunk, off, n uint32
+	at            int64 // unix nanoseconds, 0 while the line is empty
+}
+
+// len returns the number of lines stored so far, including dropped ones.
+func (s *lineStore) len() int {
+	return s.first + len(s.index)
+}
+
+// line returns line i, which can't be appended to. Lines of spilled chunks
+// are read back from the spill file.
+func (s *lineStore) line(i int) []byte {
+	r := s.index[i-s.first]
+	end := r.off + r.n
+	c := s.chunks[r.chunk]
+	if c == nil {
+		b := make([]byte, r.n)
+		n, _ := s.spill.ReadAt(b, s.spilled[r.chunk]+int64(r.off))
+		return b[:n]
+	}
+	return c[r.off:end:end]
+}
+
+// arrived returns when the first byte of line i arrived, or the zero time
+// for empty lines.
+func (s *lineStore) arrived(i int) time.Time {
+	if i < s.first || i >= s.len() {
+		return time.Time{}
+	}
+	if at := s.index[i-s.first].at; at != 0 {
+		return time.Unix(0, at)
+	}
+	return time.Time{}
+}
+
+// newLine starts an empty line.
+func (s *lineStore) newLine() {
+	if len(s.chunks) == 0 {
+		s.grow(chunkSize)
+	}
+	c := len(s.chunks) - 1
+	s.index = append(s.index, lineRef{chunk: uint32(c), off: uint32(len(s.chunks[c]))})
+	s.trim()
+}
+
+// grow adds an empty chunk of the given size.
+func (s *lineStore) grow(size int) {
+	s.chunks = append(s.chunks, make([]byte, 0, size))
+	s.size += size
+}
+
+// add appends b to the last line. A line that doesn't fit in what is left
+// of the last chunk is moved to a new one.
+func (s *lineStore) add(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	last := &s.index[len(s.index)-1]
+	if last.at == 0 {
+		last.at = time.Now().UnixNano()
+	}
+	c := s.chunks[last.chunk]
+	if len(c)+len(b) > cap(c) {
+		size := chunkSize
+		if n := 2 * (int(last.n) + len(b)); n > size {
+			size = n
+		}
+		s.grow(size)
+		s.chunks[len(s.chunks)-1] = append(s.chunks[len(s.chunks)-1], c[last.off:]...)
+		last.chunk, last.off = uint32(len(s.chunks)-1), 0
+	}
+	s.chunks[last.chunk] = append(s.chunks[last.chunk], b...)
+	last.n += uint32(len(b))
+}
+
+// truncate empties the last line. The line starts again at the end of its
+// chunk so that lines already read are left as they were.
+func (s *lineStore) truncate() {
+	last := &s.index[len(s.index)-1]
+	last.off, last.n = uint32(len(s.chunks[last.chunk])), 0
+	s.rewrites++
+}
+
+// trim drops the oldest lines, or spills their chunks, until the store is
+// within its limits. The chunk of the last line is always kept.
+func (s *lineStore) trim() {
+	for s.maxLines > 0 && len(s.index) > s.maxLines {
+		s.drop()
+	}
+	for s.maxBytes > 0 && s.size > s.maxBytes {
+		last := int(s.index[len(s.index)-1].chunk)
+		if s.low >= last {
+			return
+		}
+		if s.spill != nil && s.spillChunk(s.low) {
+			continue
+		}
+		c := s.low
+		for int(s.index[0].chunk) <= c {
+			s.drop()
+		}
+		s.free(c)
+	}
+}
+
+// drop removes the oldest line and frees the chunks no longer used.
+func (s *lineStore) drop() {
+	r := s.index[0]
+	s.index = s.index[1:]
+	s.first++
+	for c := int(r.chunk); c < int(s.index[0].chunk); c++ {
+		s.free(c)
+	}
+}
+
+// free releases chunk c and any older ones.
+func (s *lineStore) free(c int) {
+	for ; s.low <= c; s.low++ {
+		if s.chunks[s.low] != nil {
+			s.size -= cap(s.chunks[s.low])
+		}
+		s.chunks[s.low] = nil
+	}
+}
+
+// spillChunk writes chunk c to the end of the spill file and frees it, it
+// reports whether the chunk could be written.
+func (s *lineStore) spillChunk(c int) bool {
+	off, err := s.spill.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false
+	}
+	if _, err := s.spill.Write(s.chunks[c]); err != nil {
+		return false
+	}
+	for len(s.spilled) <= c {
+		s.spilled = append(s.spilled, 0)
+	}
+	s.spilled[c] = off
+	s.free(c)
+	return true
+}
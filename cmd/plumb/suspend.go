@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// catchSuspend has the main loop suspend plumb when it is sent SIGTSTP, as
+// the terminal doesn't send it for Ctrl-Z while plumb draws on it.
+func (t *terminal) catchSuspend() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTSTP)
+	go func() {
+		defer crash()
+		for range sigs {
+			t.redraws.Lock()
+			t.redraws.suspend = true
+			t.redraws.Unlock()
+			t.requestDraw()
+		}
+	}()
+}
+
+// suspend gives the terminal back and stops plumb along with the commands
+// in its pipeline, like the shell does for Ctrl-Z. Once continued plumb
+// takes the terminal again and redraws it.
+func (t *terminal) suspend() error {
+	if err := screen.Suspend(); err != nil {
+		return err
+	}
+	// SIGTSTP is caught, so the process group is stopped with SIGSTOP
+	syscall.Kill(0, syscall.SIGSTOP)
+	if err := screen.Resume(); err != nil {
+		return err
+	}
+	t.invalidate()
+	return nil
+}
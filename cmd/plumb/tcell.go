@@ -0,0 +1,175 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// tcellDisplay draws with tcell, which knows the terminal from terminfo.
+type tcellDisplay struct {
+	s       tcell.Screen
+	buttons tcell.ButtonMask // held down, so only presses are reported
+	paste   *strings.Builder // text pasted so far, nil outside of a paste
+}
+
+// newTcellDisplay takes over the terminal, with the mouse, bracketed paste
+// and focus events enabled.
+func newTcellDisplay() (*tcellDisplay, error) {
+	s, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Init(); err != nil {
+		return nil, err
+	}
+	s.EnableMouse(tcell.MouseButtonEvents)
+	s.EnablePaste()
+	s.EnableFocus()
+	return &tcellDisplay{s: s}, nil
+}
+
+func (d *tcellDisplay) Size() (int, int) {
+	return d.s.Size()
+}
+
+func (d *tcellDisplay) SetCell(x, y int, r rune, fg, bg attr) {
+	st := tcell.StyleDefault.Foreground(tcellColor(fg)).Background(tcellColor(bg))
+	st = st.Bold(fg&attrBold != 0).Underline(fg&attrUnderline != 0).Reverse(fg&attrReverse != 0)
+	d.s.SetContent(x, y, r, nil, st)
+}
+
+// tcellColor returns the tcell color of a.
+func tcellColor(a attr) tcell.Color {
+	a &= colorMask
+	switch {
+	case a == colorDefault:
+		return tcell.ColorDefault
+	case a&colorRGB != 0:
+		return tcell.NewRGBColor(int32(a>>16&0xff), int32(a>>8&0xff), int32(a&0xff))
+	}
+	return tcell.PaletteColor(int(a) - 1)
+}
+
+func (d *tcellDisplay) SetCursor(x, y int) { d.s.ShowCursor(x, y) }
+func (d *tcellDisplay) HideCursor()        { d.s.HideCursor() }
+func (d *tcellDisplay) Clear()             { d.s.Clear() }
+func (d *tcellDisplay) Close()             { d.s.Fini() }
+func (d *tcellDisplay) Suspend() error     { return d.s.Suspend() }
+func (d *tcellDisplay) Interrupt()         { d.s.PostEvent(tcell.NewEventInterrupt(nil)) }
+
+func (d *tcellDisplay) Flush() error {
+	d.s.Show()
+	return nil
+}
+
+func (d *tcellDisplay) Resume() error {
+	if err := d.s.Resume(); err != nil {
+		return err
+	}
+	d.s.Sync()
+	return nil
+}
+
+func (d *tcellDisplay) PollEvent() event {
+	for {
+		switch ev := d.s.PollEvent().(type) {
+		case nil:
+			return event{Type: eventInterrupt}
+		case *tcell.EventInterrupt:
+			return event{Type: eventInterrupt}
+		case *tcell.EventResize:
+			return event{Type: eventResize}
+		case *tcell.EventFocus:
+			return event{Type: eventFocus, Focused: ev.Focused}
+		case *tcell.EventPaste:
+			if ev.Start() {
+				d.paste = &strings.Builder{}
+				continue
+			}
+			if d.paste != nil {
+				text := d.paste.String()
+				d.paste = nil
+				return event{Type: eventPaste, Text: text}
+			}
+		case *tcell.EventKey:
+			if d.paste != nil {
+				switch {
+				case ev.Key() == tcell.KeyRune:
+					d.paste.WriteRune(ev.Rune())
+				case ev.Key() == tcell.KeyEnter:
+					d.paste.WriteByte('\n')
+				case ev.Key() == tcell.KeyTab:
+					d.paste.WriteByte('\t')
+				}
+				continue
+			}
+			if ev.Key() == tcell.KeyRune {
+				if ev.Rune() == ' ' {
+					return event{Type: eventKey, Key: "space"}
+				}
+				return event{Type: eventKey, Ch: ev.Rune()}
+			}
+			if name, ok := keyNames[ev.Key()]; ok {
+				return event{Type: eventKey, Key: name}
+			}
+		case *tcell.EventMouse:
+			x, y := ev.Position()
+			b := ev.Buttons()
+			pressed := b &^ d.buttons
+			d.buttons = b & (tcell.Button1 | tcell.Button2 | tcell.Button3)
+			for _, m := range mouseNames {
+				if pressed&m.button != 0 {
+					return event{Type: eventMouse, Key: m.name, MouseX: x, MouseY: y}
+				}
+			}
+		}
+	}
+}
+
+var mouseNames = []struct {
+	button tcell.ButtonMask
+	name   string
+}{
+	{tcell.Button1, "mouseleft"},
+	{tcell.Button3, "mousemiddle"},
+	{tcell.Button2, "mouseright"},
+	{tcell.WheelUp, "wheelup"},
+	{tcell.WheelDown, "wheeldown"},
+}
+
+var keyNames = map[tcell.Key]string{
+	tcell.KeyUp:        "up",
+	tcell.KeyDown:      "down",
+	tcell.KeyLeft:      "left",
+	tcell.KeyRight:     "right",
+	tcell.KeyPgUp:      "pgup",
+	tcell.KeyPgDn:      "pgdn",
+	tcell.KeyHome:      "home",
+	tcell.KeyEnd:       "end",
+	tcell.KeyInsert:    "insert",
+	tcell.KeyDelete:    "delete",
+	tcell.KeyEnter:     "enter",
+	tcell.KeyTab:       "tab",
+	tcell.KeyBacktab:   "backtab",
+	tcell.KeyEsc:       "esc",
+	tcell.KeyBackspace: "backspace",
+	tcell.KeyF1:        "f1",
+	tcell.KeyF2:        "f2",
+	tcell.KeyF3:        "f3",
+	tcell.KeyF4:        "f4",
+	tcell.KeyF5:        "f5",
+	tcell.KeyF6:        "f6",
+	tcell.KeyF7:        "f7",
+	tcell.KeyF8:        "f8",
+	tcell.KeyF9:        "f9",
+	tcell.KeyF10:       "f10",
+	tcell.KeyF11:       "f11",
+	tcell.KeyF12:       "f12",
+}
+
+func init() {
+	for k := tcell.KeyCtrlA; k <= tcell.KeyCtrlZ; k++ {
+		keyNames[k] = "ctrl-" + string(rune('a'+k-tcell.KeyCtrlA))
+	}
+}
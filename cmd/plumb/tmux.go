@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// inTmux returns cmd run in a pane made by the configured tmux command,
+// such as split-window -h or new-window, so plumb stays visible while the
+// editor runs. tmux returns at once, so the command is run without the
+// terminal, see detached. Outside of tmux, or when not configured, cmd is
+// returned.
+func (t *terminal) inTmux(cmd *exec.Cmd) *exec.Cmd {
+	if t.tmux == "" || os.Getenv("TMUX") == "" {
+		return cmd
+	}
+	dir := cmd.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	args := append(strings.Fields(t.tmux), "-c", dir)
+	// the pane doesn't get the environment of plumb
+	for _, e := range cmd.Env {
+		if strings.HasPrefix(e, "PLUMB_") {
+			args = append(args, "-e", e)
+		}
+	}
+	var words []string
+	for _, a := range cmd.Args {
+		words = append(words, shellQuote(a))
+	}
+	return exec.Command("tmux", append(args, strings.Join(words, " "))...)
+}
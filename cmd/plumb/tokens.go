@@ -0,0 +1,89 @@
+package main
+
+import "github.com/satran/plumb"
+
+// selection caches the matches of the selected line and which of them is
+// active.
+type selection struct {
+	index   int // input line the matches belong to
+	length  int // length of the line when it was matched
+	row     int // row the matches were found on, see frameRe
+	matches []*plumb.Match
+	active  int
+	chosen  bool // the active token was picked by the user
+}
+
+// matches returns the plumbable tokens on the selected line.
+func (t *terminal) matches() *selection {
+	n := t.view.Index(t.selline)
+	line, _ := t.view.Line(t.selline)
+	if t.sel == nil || t.sel.index != n || t.sel.length != len(line) {
+		t.sel = &selection{index: n, length: len(line)}
+		t.sel.matches, t.sel.row = t.lineMatches(t.selline)
+	}
+	return t.sel
+}
+
+// lineMatches returns the plumbable tokens of row and the row they were
+// found on, which differs from row for Go stack frames.
+func (t *terminal) lineMatches(row int) ([]*plumb.Match, int) {
+	n := t.view.Index(row)
+	line, _ := t.view.Line(row)
+	matches := plumb.MatchLine(t.rules, string(line), t.dir(n))
+	if m := t.diffMatch(n); m != nil {
+		matches = append([]*plumb.Match{m}, matches...)
+	}
+	if len(matches) == 0 && frameRe.Match(line) {
+		return t.frameMatches(row)
+	}
+	return matches, row
+}
+
+// active returns the active token on the selected line or nil if there is
+// nothing to plumb.
+func (t *terminal) active() *plumb.Match {
+	s := t.matches()
+	if len(s.matches) == 0 {
+		return nil
+	}
+	return s.matches[s.active]
+}
+
+// cycleToken makes the next token in direction dir active, wrapping around
+// at the ends of the line.
+func (t *terminal) cycleToken(dir int) {
+	s := t.matches()
+	if len(s.matches) == 0 {
+		return
+	}
+	s.active = (s.active + dir + len(s.matches)) % len(s.matches)
+	s.chosen = true
+	// scroll the active token into view
+	line, _ := t.view.Line(s.row)
+	m := s.matches[s.active]
+	if start, end := t.column(line, m.Start), t.column(line, m.End); start < t.left {
+		t.left = start
+	} else if end > t.left+t.cols {
+		t.left = end - t.cols
+	}
+}
+
+// maxScan is the most lines nextTarget looks through, as checking tokens
+// for files can take a while.
+const maxScan = 10000
+
+// nextTarget selects the next line in direction dir that has something to
+// plumb, skipping the lines in between.
+func (t *terminal) nextTarget(dir int) {
+	rows := t.view.Rows()
+	for i, n := 0, t.selline+dir; i < maxScan && n >= 0 && n < rows; i, n = i+1, n+dir {
+		if ll := t.lineLinks(n); ll != nil && len(ll.tokens) == 0 {
+			continue
+		}
+		if ms, _ := t.lineMatches(n); len(ms) > 0 {
+			t.gotoLine(n)
+			return
+		}
+	}
+	t.message = "no more lines to plumb"
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// view maps the rows shown on the screen to lines of the input so the input
+// can be narrowed down without copying it.
+type view struct {
+	sync.Mutex
+	src     *lineReader
+	filter  func(line []byte) bool // nil shows every line
+	dedup   bool                   // show runs of identical lines once
+	index   []int                  // input line of every row when filtered or deduplicated
+	counts  []int                  // lines in the run of every row when deduplicated
+	sorted  []int                  // input lines of the first rows reordered, see SetOrder
+	scanned int                    // input lines checked against filter
+	first   int                    // first input line when last updated
+	dropped int                    // rows dropped from the top, see Shift
+}
+
+// indexed reports whether rows are mapped to lines by index.
+func (v *view) indexed() bool {
+	return v.filter != nil || v.dedup
+}
+
+// update checks lines that arrived since the last call against the filter,
+// and the line of the last row when deduplicating, and forgets the rows of
+// lines dropped by the input.
+func (v *view) update() {
+	first, rows := v.src.bounds()
+	if !v.indexed() {
+		if first > v.first {
+			v.dropped += first - v.first
+		}
+		v.first = first
+		v.dropSorted()
+		return
+	}
+	n := sort.SearchInts(v.index, first)
+	v.index = v.index[n:]
+	if v.dedup {
+		v.counts = v.counts[n:]
+	}
+	v.dropped += n
+	v.first = first
+	v.dropSorted()
+	if v.scanned < first {
+		v.scanned = first
+	}
+	for ; v.scanned < rows; v.scanned++ {
+		line, _ := v.src.Line(v.scanned)
+		if v.filter != nil && !v.filter(line) {
+			continue
+		}
+		if last := len(v.index) - 1; v.dedup && last >= 0 {
+			if prev, _ := v.src.Line(v.index[last]); bytes.Equal(prev, line) {
+				v.counts[last]++
+				continue
+			}
+		}
+		v.index = append(v.index, v.scanned)
+		if v.dedup {
+			v.counts = append(v.counts, 1)
+		}
+	}
+}
+
+func (v *view) Rows() int {
+	v.Lock()
+	defer v.Unlock()
+	v.update()
+	if !v.indexed() {
+		return v.src.Rows() - v.first
+	}
+	return len(v.index)
+}
+
+func (v *view) Line(i int) ([]byte, error) {
+	n := v.Index(i)
+	if n < 0 {
+		return nil, errors.New("line not found")
+	}
+	return v.src.Line(n)
+}
+
+// Spans returns the styles of row i.
+func (v *view) Spans(i int) []span {
+	n := v.Index(i)
+	if n < 0 {
+		return nil
+	}
+	return v.src.Spans(n)
+}
+
+// dropSorted forgets the reordered rows of lines dropped by the input.
+func (v *view) dropSorted() {
+	if v.sorted == nil {
+		return
+	}
+	kept := v.sorted[:0]
+	for _, n := range v.sorted {
+		if n >= v.first {
+			kept = append(kept, n)
+		}
+	}
+	v.sorted = kept
+}
+
+// Index returns the input line shown in row i or -1 if there is none.
+func (v *view) Index(i int) int {
+	v.Lock()
+	defer v.Unlock()
+	v.update()
+	if i >= 0 && i < len(v.sorted) {
+		return v.sorted[i]
+	}
+	return v.unsorted(i)
+}
+
+// unsorted returns the input line of row i in input order.
+func (v *view) unsorted(i int) int {
+	if !v.indexed() {
+		if i < 0 || v.first+i >= v.src.Rows() {
+			return -1
+		}
+		return v.first + i
+	}
+	if i < 0 || i >= len(v.index) {
+		return -1
+	}
+	return v.index[i]
+}
+
+// Find returns the first row showing input line n or a later one. Among
+// reordered rows it is the row of the line after n in the input.
+func (v *view) Find(n int) int {
+	v.Lock()
+	defer v.Unlock()
+	v.update()
+	row := v.findUnsorted(n)
+	if row >= len(v.sorted) {
+		return row
+	}
+	row = -1
+	for i, l := range v.sorted {
+		if l >= n && (row < 0 || l < v.sorted[row]) {
+			row = i
+		}
+	}
+	if row < 0 {
+		return len(v.sorted)
+	}
+	return row
+}
+
+// findUnsorted returns the row of input line n or a later one in input
+// order.
+func (v *view) findUnsorted(n int) int {
+	if !v.indexed() {
+		if n < v.first {
+			return 0
+		}
+		return n - v.first
+	}
+	return sort.SearchInts(v.index, n)
+}
+
+// Shift returns the number of rows dropped from the top since the last call,
+// by which the rows below moved up.
+func (v *view) Shift() int {
+	v.Lock()
+	defer v.Unlock()
+	v.update()
+	n := v.dropped
+	v.dropped = 0
+	return n
+}
+
+// SetFilter narrows the view to the lines for which f returns true, a nil f
+// shows every line.
+func (v *view) SetFilter(f func(line []byte) bool) {
+	v.Lock()
+	defer v.Unlock()
+	v.filter = f
+	v.index, v.counts, v.sorted = nil, nil, nil
+	v.scanned = 0
+	v.dropped = 0
+}
+
+// SetDedup shows runs of identical lines once, or every line again.
+func (v *view) SetDedup(on bool) {
+	v.Lock()
+	defer v.Unlock()
+	v.dedup = on
+	v.index, v.counts, v.sorted = nil, nil, nil
+	v.scanned = 0
+	v.dropped = 0
+}
+
+// SetOrder shows the input lines in the order of lines, which are those of
+// the first rows, before the rows of the lines after them. A nil lines
+// shows the rows in input order again.
+func (v *view) SetOrder(lines []int) {
+	v.Lock()
+	defer v.Unlock()
+	v.sorted = lines
+}
+
+// Sorted reports whether rows are reordered.
+func (v *view) Sorted() bool {
+	v.Lock()
+	defer v.Unlock()
+	return v.sorted != nil
+}
+
+// Count returns the number of lines in the run row i shows, 1 unless
+// deduplicating.
+func (v *view) Count(i int) int {
+	v.Lock()
+	defer v.Unlock()
+	v.update()
+	if i >= 0 && i < len(v.sorted) {
+		i = v.findUnsorted(v.sorted[i])
+	}
+	if !v.dedup || i < 0 || i >= len(v.counts) {
+		return 1
+	}
+	return v.counts[i]
+}
+
+// Reset forgets the rows of an input that was reset.
+func (v *view) Reset() {
+	v.Lock()
+	defer v.Unlock()
+	v.index, v.counts, v.sorted = nil, nil, nil
+	v.scanned, v.first, v.dropped = 0, 0, 0
+}
+
+// fuzzy reports whether the runes of pattern appear in s in order. The match
+// ignores case unless pattern has upper case letters.
+func fuzzy(pattern string, s []byte) bool {
+	fold := true
+	for _, r := range pattern {
+		if unicode.IsUpper(r) {
+			fold = false
+			break
+		}
+	}
+	for _, p := range pattern {
+		for {
+			if len(s) == 0 {
+				return false
+			}
+			r, size := utf8.DecodeRune(s)
+			s = s[size:]
+			if fold {
+				r = unicode.ToLower(r)
+			}
+			if r == p {
+				break
+			}
+		}
+	}
+	return true
+}
+
+// startFilter opens the filter prompt. The view narrows to the lines fuzzily
+// matching the text as it is typed and enter plumbs the selected line.
+func (t *terminal) startFilter() {
+	t.prompt = &prompt{
+		label: "filter: ",
+		change: func(text string) {
+			if text == "" {
+				t.narrow(nil)
+			} else {
+				t.narrow(func(line []byte) bool { return fuzzy(text, line) })
+			}
+		},
+		done: func(text string) error {
+			t.exec()
+			return nil
+		},
+		cancel: func() {
+			t.narrow(nil)
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/satran/plumb"
+)
+
+// inVisual reports whether row is part of the visual selection.
+func (t *terminal) inVisual(row int) bool {
+	if !t.visual {
+		return false
+	}
+	lo, hi := t.anchor, t.selline
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return lo <= row && row <= hi
+}
+
+// openAll plumbs the first token of every line in the visual selection.
+// Files go to a single editor when it takes several files at once.
+func (t *terminal) openAll() error {
+	lo, hi := t.anchor, t.selline
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	t.visual = false
+	var files, others []*plumb.Match
+	seen := map[string]bool{}
+	for row := lo; row <= hi; row++ {
+		ms, _ := t.lineMatches(row)
+		if len(ms) == 0 {
+			continue
+		}
+		m := ms[0]
+		if key := m.Attrs["file"] + ":" + m.Attrs["line"]; m.Rule.To == "edit" && m.Rule.Start == nil {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if _, ok := t.opener(m.Attrs["file"]); !ok && plumb.IsText(m.Attrs["file"]) {
+				files = append(files, m)
+				continue
+			}
+		}
+		others = append(others, m)
+	}
+	if len(files)+len(others) == 0 {
+		return errors.New("no files found in the selection")
+	}
+	var cmds []string // shown in dry run mode
+	if args := multiEditorArgs(t.editor, files); args != nil {
+		cmd := t.inTmux(exec.Command(args[0], args[1:]...))
+		if t.dryrun {
+			cmds = append(cmds, describe(cmd))
+		} else {
+			for _, m := range files {
+				t.visit(m)
+				t.record(m)
+			}
+			if err := t.run(cmd); err != nil {
+				return err
+			}
+		}
+	} else {
+		others = append(files, others...)
+	}
+	for _, m := range others {
+		if t.dryrun {
+			cmd, err := t.command(m)
+			if err != nil {
+				return err
+			}
+			cmds = append(cmds, describe(cmd))
+			continue
+		}
+		if err := t.plumb(m); err != nil {
+			return err
+		}
+	}
+	if t.dryrun {
+		t.message = strings.Join(cmds, "; ")
+	}
+	return nil
+}
+
+// multiEditorArgs returns the command line opening all files in one editor,
+// or nil if the editor can't do that.
+func multiEditorArgs(editor string, files []*plumb.Match) []string {
+	if len(files) == 0 || editor == "" || strings.Contains(editor, "{file}") {
+		return nil
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return nil
+	}
+	switch filepath.Base(args[0]) {
+	case "vi", "vim", "nvim", "gvim":
+		// tabs can't be given a line each
+		args = append(args, "-p")
+		seen := map[string]bool{}
+		for _, m := range files {
+			if !seen[m.Attrs["file"]] {
+				seen[m.Attrs["file"]] = true
+				args = append(args, m.Attrs["file"])
+			}
+		}
+		return args
+	case "emacs", "emacsclient":
+		for _, m := range files {
+			if line := m.Line(); line > 0 {
+				args = append(args, "+"+strconv.Itoa(line))
+			}
+			args = append(args, m.Attrs["file"])
+		}
+		return noWait(args)
+	}
+	return nil
+}
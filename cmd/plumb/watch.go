@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// settle is how long changes have to stop before the command is run again,
+// so saving many files runs it once.
+const settle = 200 * time.Millisecond
+
+// watchList is the -watch flag, which can be given more than once.
+type watchList []string
+
+func (w *watchList) String() string {
+	return strings.Join(*w, " ")
+}
+
+func (w *watchList) Set(s string) error {
+	*w = append(*w, filepath.Clean(s))
+	return nil
+}
+
+// watch runs the command again when files matching the patterns change. A
+// pattern is a directory, watched with everything below it, or a file name
+// that can have glob(7) meta characters.
+func (t *terminal) watch(patterns []string) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, p := range patterns {
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			err = filepath.WalkDir(p, func(path string, d os.DirEntry, err error) error {
+				if err != nil || !d.IsDir() {
+					return err
+				}
+				if path != p && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return w.Add(path)
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		dirs, _ := filepath.Glob(filepath.Dir(p))
+		if len(dirs) == 0 {
+			return &os.PathError{Op: "watch", Path: p, Err: os.ErrNotExist}
+		}
+		for _, d := range dirs {
+			if err := w.Add(d); err != nil {
+				return err
+			}
+		}
+	}
+	go func() {
+		defer crash()
+		var timer *time.Timer
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if ev.Op == fsnotify.Chmod || !watched(patterns, ev.Name) {
+					continue
+				}
+				debug("watch: %v", ev)
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(settle, t.requestRerun)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				debug("watch: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// watched reports whether a change to name concerns one of the patterns.
+func watched(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if name == p || strings.HasPrefix(name, p+string(filepath.Separator)) {
+			return true
+		}
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
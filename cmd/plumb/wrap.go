@@ -0,0 +1,46 @@
+package main
+
+// height returns the number of screen rows line n takes up.
+func (t *terminal) height(n int) int {
+	if !t.wrap || t.cols <= 0 {
+		return 1
+	}
+	line, err := t.view.Line(n)
+	if err != nil {
+		return 1
+	}
+	w := t.width(line)
+	if w <= t.cols {
+		return 1
+	}
+	return (w + t.cols - 1) / t.cols
+}
+
+// rowsBetween returns the number of screen rows lines from up to to take up.
+func (t *terminal) rowsBetween(from, to int) int {
+	if !t.wrap {
+		return to - from
+	}
+	rows := 0
+	for n := from; n < to; n++ {
+		rows += t.height(n)
+	}
+	return rows
+}
+
+// lineAt returns the line drawn on screen row y and the column of the line
+// the row starts at.
+func (t *terminal) lineAt(y int) (int, int) {
+	if !t.wrap {
+		return t.topline + y, t.left
+	}
+	n := t.topline
+	for {
+		h := t.height(n)
+		if y < h {
+			return n, y * t.cols
+		}
+		y -= h
+		n++
+	}
+}
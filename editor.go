@@ -0,0 +1,133 @@
+package plumb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Browser returns the command used to open urls, $BROWSER if set or else
+// the platform's opener.
+func Browser() string {
+	if b := os.Getenv("BROWSER"); b != "" {
+		return b
+	}
+	return Opener()
+}
+
+// Opener returns the platform's opener, which opens files and urls in the
+// application registered for their type: open on macOS, else xdg-open.
+func Opener() string {
+	if runtime.GOOS == "darwin" {
+		return "open"
+	}
+	return "xdg-open"
+}
+
+// IsText reports whether file looks like text to be opened in an editor,
+// from the start of its content. Directories and files that can't be read,
+// such as ones yet to be made, count as text.
+func IsText(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	b := make([]byte, 512)
+	n, err := io.ReadFull(f, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return true
+	}
+	b = b[:n]
+	if bytes.IndexByte(b, 0) >= 0 {
+		return false
+	}
+	// the last rune can be cut short
+	for i := 0; i < utf8.UTFMax && len(b) > 0 && !utf8.Valid(b); i++ {
+		b = b[:len(b)-1]
+	}
+	return utf8.Valid(b)
+}
+
+// EditorArgs returns the command line that opens file at line and col in
+// editor. Line and col are ignored when zero. An editor containing {file} is
+// a template, see expandEditor. Without an editor the platform's opener
+// opens file.
+func EditorArgs(editor, file string, line, col int) []string {
+	if strings.Contains(editor, "{file}") {
+		return expandEditor(editor, file, line, col)
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return []string{Opener(), file}
+	}
+	if line <= 0 {
+		return append(args, file)
+	}
+	switch filepath.Base(args[0]) {
+	case "vi", "vim", "nvim", "gvim":
+		if col > 0 {
+			return append(args, fmt.Sprintf("+call cursor(%d,%d)", line, col), file)
+		}
+	case "code", "codium", "code-insiders":
+		if col > 0 {
+			return append(args, "--goto", fmt.Sprintf("%s:%d:%d", file, line, col))
+		}
+		return append(args, "--goto", fmt.Sprintf("%s:%d", file, line))
+	case "emacs", "emacsclient":
+		if col > 0 {
+			return append(args, fmt.Sprintf("+%d:%d", line, col), file)
+		}
+	}
+	return append(args, fmt.Sprintf("+%d", line), file)
+}
+
+var placeholderRe = regexp.MustCompile(`:?\{(file|line|col)\}`)
+
+// expandEditor expands the {file}, {line} and {col} placeholders in the
+// editor template, which is split into words with rc quoting like a rule.
+// Without a line, words holding only line or col placeholders are dropped
+// and :{line} or :{col} is cut from the rest. Without a col, col is 1.
+func expandEditor(editor, file string, line, col int) []string {
+	words, err := SplitFields(editor)
+	if err != nil {
+		words = strings.Fields(editor)
+	}
+	if col <= 0 {
+		col = 1
+	}
+	var args []string
+	for _, w := range words {
+		if line <= 0 && !strings.Contains(w, "{file}") && placeholderRe.MatchString(w) {
+			continue
+		}
+		args = append(args, placeholderRe.ReplaceAllStringFunc(w, func(p string) string {
+			colon := ""
+			if strings.HasPrefix(p, ":") {
+				colon, p = ":", p[1:]
+			}
+			switch p {
+			case "{file}":
+				return colon + file
+			case "{line}":
+				if line <= 0 {
+					return ""
+				}
+				return colon + strconv.Itoa(line)
+			default:
+				if line <= 0 {
+					return ""
+				}
+				return colon + strconv.Itoa(col)
+			}
+		}))
+	}
+	return args
+}
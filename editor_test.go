@@ -0,0 +1,33 @@
+package plumb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditorArgs(t *testing.T) {
+	tests := []struct {
+		editor    string
+		line, col int
+		want      []string
+	}{
+		{"vi", 0, 0, []string{"vi", "f.go"}},
+		{"vi", 3, 0, []string{"vi", "+3", "f.go"}},
+		{"nvim -R", 3, 2, []string{"nvim", "-R", "+call cursor(3,2)", "f.go"}},
+		{"code", 3, 0, []string{"code", "--goto", "f.go:3"}},
+		{"code", 3, 2, []string{"code", "--goto", "f.go:3:2"}},
+		{"emacsclient -n", 3, 2, []string{"emacsclient", "-n", "+3:2", "f.go"}},
+		{"ed", 3, 2, []string{"ed", "+3", "f.go"}},
+		{"hx {file}:{line}:{col}", 3, 0, []string{"hx", "f.go:3:1"}},
+		{"hx {file}:{line}:{col}", 0, 0, []string{"hx", "f.go"}},
+		{"subl '{file}:{line}'", 3, 0, []string{"subl", "f.go:3"}},
+		{"", 3, 0, []string{Opener(), "f.go"}},
+		{"  \t", 3, 0, []string{Opener(), "f.go"}},
+	}
+	for _, tt := range tests {
+		got := EditorArgs(tt.editor, "f.go", tt.line, tt.col)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EditorArgs(%q, %d, %d) = %q, want %q", tt.editor, tt.line, tt.col, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package plumb
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// pathLike matches paths worth searching for when they don't exist: ones
+// with a directory or an extension.
+var pathLike = regexp.MustCompile(`/|\.[A-Za-z0-9]+$`)
+
+// maxIndex bounds the number of files in the project index.
+const maxIndex = 200000
+
+// find looks for the file a path that doesn't exist refers to. Relative
+// paths are tried against the parent directories of dir and the root of its
+// git repository. Failing that the file of the repository whose path shares
+// the longest ending with path is used, so truncated paths and paths from
+// other machines still resolve.
+func find(dir, path string) (string, bool) {
+	start := dir
+	if start == "" {
+		start, _ = os.Getwd()
+	}
+	if !filepath.IsAbs(path) {
+		for d := start; ; d = filepath.Dir(d) {
+			if p, ok := exists(d, path); ok {
+				return p, true
+			}
+			if filepath.Dir(d) == d {
+				break
+			}
+		}
+		if root := gitRoot(start); root != "" {
+			if p, ok := exists(root, path); ok {
+				return p, true
+			}
+		}
+	}
+	if idx := projectIndex(start); idx != nil {
+		return idx.lookup(path)
+	}
+	return "", false
+}
+
+// gitRoot returns the root of the git repository dir is in or "".
+func gitRoot(dir string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		if filepath.Dir(d) == d {
+			return ""
+		}
+	}
+}
+
+// fileIndex lists the files of a project by their base name.
+type fileIndex struct {
+	once  sync.Once
+	root  string
+	files map[string][]string
+}
+
+var (
+	indexMu sync.Mutex
+	indexes = map[string]*fileIndex{}
+)
+
+// projectIndex returns the index of the git repository dir is in, or nil
+// outside of a repository. Indexes are built on first use.
+func projectIndex(dir string) *fileIndex {
+	root := gitRoot(dir)
+	if root == "" {
+		return nil
+	}
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	idx, ok := indexes[root]
+	if !ok {
+		idx = &fileIndex{root: root}
+		indexes[root] = idx
+	}
+	return idx
+}
+
+func (idx *fileIndex) build() {
+	idx.files = map[string][]string{}
+	n := 0
+	filepath.Walk(idx.root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := fi.Name()
+		if fi.IsDir() {
+			if path != idx.root && (strings.HasPrefix(name, ".") || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if n++; n > maxIndex {
+			return filepath.SkipDir
+		}
+		idx.files[name] = append(idx.files[name], path)
+		return nil
+	})
+	Debug("indexed %d files in %s", n, idx.root)
+}
+
+// lookup returns the indexed file sharing the most trailing path elements
+// with path. Paths with a directory need to share more than the base name.
+func (idx *fileIndex) lookup(path string) (string, bool) {
+	idx.once.Do(idx.build)
+	want := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+	need := 1
+	if len(want) > 1 {
+		need = 2
+	}
+	best, bestN := "", 0
+	for _, f := range idx.files[want[len(want)-1]] {
+		have := strings.Split(filepath.ToSlash(f), "/")
+		n := 0
+		for n < len(want) && n < len(have) && want[len(want)-1-n] == have[len(have)-1-n] {
+			n++
+		}
+		if n > bestN || n == bestN && len(f) < len(best) {
+			best, bestN = f, n
+		}
+	}
+	if bestN < need {
+		return "", false
+	}
+	return best, true
+}
@@ -0,0 +1,14 @@
+package plumb
+
+import "os/exec"
+
+// IsCommit reports whether rev names a commit of the git repository dir is
+// in, the working directory when dir is empty.
+func IsCommit(dir, rev string) bool {
+	if rev == "" || rev[0] == '-' {
+		return false
+	}
+	cmd := exec.Command("git", "cat-file", "-e", rev+"^{commit}")
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
@@ -0,0 +1,116 @@
+package plumb
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Resolve returns the existing file path refers to, relative paths are
+// taken relative to dir unless it is empty. Backslash escaped characters
+// such as in My\ Documents are unescaped if needed. Paths that don't exist
+// as given are looked for with find.
+func Resolve(dir, path string) (string, bool) {
+	if p, ok := exists(dir, path); ok {
+		return p, true
+	}
+	if strings.Contains(path, `\`) {
+		path = unescape(path)
+		if p, ok := exists(dir, path); ok {
+			return p, true
+		}
+	}
+	if !pathLike.MatchString(path) {
+		return "", false
+	}
+	return find(dir, path)
+}
+
+// IsCommand reports whether name is a command found in $PATH. Names with
+// a slash are paths, not commands.
+func IsCommand(name string) bool {
+	if name == "" || strings.Contains(name, "/") {
+		return false
+	}
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func exists(dir, path string) (string, bool) {
+	if dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+var (
+	wordRe     = regexp.MustCompile(`\S+`)
+	locationRe = regexp.MustCompile(`^(.+?)(:([0-9]+))?(:([0-9]+))?$`)
+	// spaceRule opens the paths found by spacedPaths.
+	spaceRule = &Rule{Name: "path with spaces", To: "edit"}
+)
+
+// maxWords is the most words spacedPaths joins into a path.
+const maxWords = 8
+
+// spacedPaths finds unquoted paths with spaces in them, like
+// /Users/me/My Documents/notes.txt:12, by joining words that follow one with
+// a slash in it for as long as the result names an existing file. The
+// longest such path wins.
+func spacedPaths(line, dir string) []*Match {
+	var matches []*Match
+	words := wordRe.FindAllStringIndex(line, -1)
+	for i := 0; i < len(words); i++ {
+		if !strings.Contains(line[words[i][0]:words[i][1]], "/") {
+			continue
+		}
+		last := i + maxWords - 1
+		if last >= len(words) {
+			last = len(words) - 1
+		}
+		for j := last; j > i; j-- {
+			text := strings.TrimRight(line[words[i][0]:words[j][1]], ":,.;)")
+			loc := locationRe.FindStringSubmatch(text)
+			if loc == nil {
+				continue
+			}
+			file, ok := Resolve(dir, loc[1])
+			if !ok {
+				continue
+			}
+			m := &Match{
+				Rule:  spaceRule,
+				Text:  text,
+				Start: words[i][0],
+				End:   words[i][0] + len(text),
+				Attrs: map[string]string{"data": text, "file": file, "wdir": dir},
+			}
+			if loc[3] != "" {
+				m.Attrs["line"] = loc[3]
+				if loc[5] != "" {
+					m.Attrs["col"] = loc[5]
+				}
+			}
+			matches = append(matches, m)
+			i = j
+			break
+		}
+	}
+	return matches
+}
@@ -0,0 +1,114 @@
+// Package plumb finds the files, urls and other targets in lines of text
+// using plumbing rules in the style of plan9's plumb(6) and opens them.
+package plumb
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Debug logs what the package does, by default nothing.
+var Debug = func(format string, v ...interface{}) {}
+
+// Trace logs every decision the rules make on a line, as key=value pairs,
+// by default nothing.
+var Trace = func(format string, v ...interface{}) {}
+
+// ConfigDir is the directory holding the rules file.
+func ConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "plumb")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".config", "plumb")
+}
+
+// MatchTarget returns the match for a single target, such as a command
+// line argument, or nil. A target naming an existing file is taken as a
+// whole, so it can have spaces, otherwise the first match of the rules is
+// used. Relative paths are resolved against dir.
+func MatchTarget(rules []*Rule, target, dir string) *Match {
+	if file, ok := exists(dir, target); ok {
+		return &Match{
+			Rule:  spaceRule,
+			Text:  target,
+			End:   len(target),
+			Attrs: map[string]string{"data": target, "file": file, "wdir": dir},
+		}
+	}
+	ms := MatchLine(rules, target, dir)
+	if len(ms) == 0 {
+		return nil
+	}
+	return ms[0]
+}
+
+// Command returns the command carrying out the action of m, run in the
+// directory of m and with m in its environment as by Env. Files are opened
+// with editor, see EditorArgs, or with the platform's opener when editor is
+// empty or the file isn't text.
+func Command(m *Match, editor string) (*exec.Cmd, error) {
+	var args []string
+	switch {
+	case m.Rule.Start != nil:
+		for _, a := range m.Rule.Start {
+			args = append(args, m.Expand(a))
+		}
+	case m.Rule.To == "edit" && (strings.TrimSpace(editor) == "" || !IsText(m.File())):
+		args = []string{Opener(), m.File()}
+	case m.Rule.To == "edit":
+		args = EditorArgs(editor, m.File(), m.Line(), m.Col())
+	case m.Rule.To == "web":
+		args = append(strings.Fields(Browser()), m.Text)
+	case m.Rule.To == "mail":
+		args = []string{Opener(), "mailto:" + strings.TrimPrefix(m.Text, "mailto:")}
+	default:
+		return nil, fmt.Errorf("unknown port %q", m.Rule.To)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = m.Attrs["wdir"]
+	cmd.Env = append(os.Environ(), Env(m)...)
+	return cmd, nil
+}
+
+// Env returns the environment describing m to the commands run for it, so
+// that scripts can use it instead of their arguments: $PLUMB_FILE,
+// $PLUMB_LINE, $PLUMB_COL, $PLUMB_TEXT and $PLUMB_WDIR.
+func Env(m *Match) []string {
+	wdir := m.Attrs["wdir"]
+	if wdir == "" {
+		wdir, _ = os.Getwd()
+	}
+	return []string{
+		"PLUMB_FILE=" + m.Attrs["file"],
+		"PLUMB_LINE=" + m.Attrs["line"],
+		"PLUMB_COL=" + m.Attrs["col"],
+		"PLUMB_TEXT=" + m.Text,
+		"PLUMB_WDIR=" + wdir,
+	}
+}
+
+// Open plumbs target with the user's rules and the default rules, opening
+// files in $EDITOR or the platform's opener. Relative paths are taken from
+// the working directory.
+func Open(target string) error {
+	rules, err := LoadRules(filepath.Join(ConfigDir(), "rules"))
+	if err != nil {
+		return err
+	}
+	m := MatchTarget(rules, target, "")
+	if m == nil {
+		return fmt.Errorf("%s: no rule matches", target)
+	}
+	cmd, err := Command(m, os.Getenv("EDITOR"))
+	if err != nil {
+		return err
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", cmd.Args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,474 @@
+package plumb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultRules are used after any rules read from the user's rules file.
+const DefaultRules = `
+# urls
+data matches '(https?|file)://[^ \t"''<>]*[^ \t"''<>.,;:)]'
+plumb to web
+
+# mail addresses
+data matches '[a-zA-Z0-9._%+-]+@[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*\.[a-zA-Z]+'
+plumb to mail
+
+# go stack frames: <tab>/path/to/file.go:123 +0x1f
+data matches '^\t([^ \t:]+\.go):([0-9]+)( \+0x[0-9a-f]+)?$'
+arg isfile $1
+data set $1:$2
+attr add line=$2
+plumb to edit
+
+# python tracebacks: File "/path/to/mod.py", line 42, in func
+data matches 'File "([^"]+)", line ([0-9]+)'
+arg isfile $1
+data set $1:$2
+attr add line=$2
+plumb to edit
+
+# rust: --> src/main.rs:4:5
+data matches '--> ([^ \t:"''()<>\[\],]+):([0-9]+):([0-9]+)'
+arg isfile $1
+data set $1:$2:$3
+attr add line=$2 col=$3
+plumb to edit
+
+# gcc and clang: file.c:12:3: error: message
+data matches '([^ \t:"''()<>\[\],]+):([0-9]+):([0-9]+): (fatal error|error|warning|note):'
+arg isfile $1
+data set $1:$2:$3
+attr add line=$2 col=$3 severity=$4
+plumb to edit
+
+# git diff headers: diff --git a/path b/path, --- a/path and +++ b/path
+data matches '^(diff --git a/.* b/|--- a/|\+\+\+ b/)(.+)$'
+arg isfile $2
+data set $2
+plumb to edit
+
+# git status --short: XY path or XY orig -> path
+data matches '^[ MTADRCU?!]{2} (.* -> )?(.+)$'
+arg isfile $2
+data set $2
+plumb to edit
+
+# quoted paths: "My Documents/notes.txt":12 or 'notes.txt', line 12
+data matches '"([^"]+)"(:([0-9]+)|, line ([0-9]+))?'
+arg isfile $1
+data set $1
+attr add line=$3$4
+plumb to edit
+
+data matches '''([^'']+)''(:([0-9]+)|, line ([0-9]+))?'
+arg isfile $1
+data set $1
+attr add line=$3$4
+plumb to edit
+
+# escaped spaces: My\ Documents/notes.txt:12
+data matches '(([^ \t:"''()<>\[\],\\]|\\.)*\\ ([^ \t:"''()<>\[\],\\]|\\.)*)(:([0-9]+))?'
+arg isfile $1
+attr add line=$5
+plumb to edit
+
+# grep -n and rg --vimgrep: path:line:col:text, the path may have spaces
+data matches '^([^:]+):([0-9]+):(([0-9]+):)?'
+arg isfile $1
+data set $1:$2
+attr add line=$2 col=$4
+plumb to edit
+
+# rg and grep -r without line numbers: path:text
+data matches '^([^:]+):([^0-9]|$)'
+arg isfile $1
+data set $1
+plumb to edit
+
+# file:line:col
+data matches '([^ \t:"''()<>\[\],]+):([0-9]+):([0-9]+)'
+arg isfile $1
+attr add line=$2 col=$3
+plumb to edit
+
+# file:line
+data matches '([^ \t:"''()<>\[\],]+):([0-9]+)'
+arg isfile $1
+attr add line=$2
+plumb to edit
+
+# man pages: printf(3)
+data matches '([a-zA-Z0-9_.:+-]+)\(([0-9][a-z]*)\)'
+plumb to term
+plumb start man $2 $1
+
+# file
+data matches '[^ \t:"''()<>\[\],]+'
+arg isfile $0
+plumb to edit
+
+# git commits: abbreviated or full hashes as in git log --oneline
+data matches '\b[0-9a-f]{7,40}\b'
+arg iscommit $0
+plumb to term
+plumb start git show $0
+`
+
+// Rule is a single plumbing rule in the style of plan9's plumb(6). A rule
+// matches when its pattern matches and all its checks pass, the action is
+// then run with the submatches and attributes expanded.
+type Rule struct {
+	Name     string // first line of the comment before the rule
+	Pattern  *regexp.Regexp
+	IsFile   string      // expands to a path that has to exist
+	IsCmd    string      // expands to a command that has to be in $PATH
+	IsCommit string      // expands to a commit of the git repository
+	Data     string      // replaces the matched text when set
+	Attrs    [][2]string // name=value pairs in the order they were added
+	To       string      // port the message is sent to: edit, web, mail or term
+	Start    []string    // command to run instead of sending to a port
+}
+
+// Match is a successful application of a rule to a line.
+type Match struct {
+	Rule       *Rule
+	Text       string // text matched by the pattern, or as set by the rule
+	Start, End int    // offsets of text in the line
+	Subs       []string
+	Attrs      map[string]string
+}
+
+// File is the file m refers to, or its text when it has none.
+func (m *Match) File() string {
+	if f := m.Attrs["file"]; f != "" {
+		return f
+	}
+	return m.Text
+}
+
+// Line is the line of the file m refers to, or 0.
+func (m *Match) Line() int {
+	n, _ := strconv.Atoi(m.Attrs["line"])
+	return n
+}
+
+// Col is the column of the line m refers to, or 0.
+func (m *Match) Col() int {
+	n, _ := strconv.Atoi(m.Attrs["col"])
+	return n
+}
+
+// LoadRules reads the rules in path followed by the default rules. A missing
+// rules file is not an error.
+func LoadRules(path string) ([]*Rule, error) {
+	var rules []*Rule
+	f, err := os.Open(path)
+	if err == nil {
+		defer f.Close()
+		rules, err = ParseRules(path, f)
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	defaults, err := ParseRules("default", strings.NewReader(DefaultRules))
+	if err != nil {
+		return nil, err
+	}
+	return append(rules, defaults...), nil
+}
+
+// ParseRules parses blank line separated rules. Lines starting with # are
+// comments, the first line of the one before a rule names it.
+func ParseRules(name string, r io.Reader) ([]*Rule, error) {
+	var rules []*Rule
+	var cur *Rule
+	var comment string
+	end := func(lineno int) error {
+		if cur == nil {
+			return nil
+		}
+		if cur.Pattern == nil {
+			return fmt.Errorf("%s:%d: rule has no data matches", name, lineno)
+		}
+		if cur.To == "" && cur.Start == nil {
+			return fmt.Errorf("%s:%d: rule has no plumb action", name, lineno)
+		}
+		if cur.To == "term" && cur.Start == nil {
+			return fmt.Errorf("%s:%d: term port needs plumb start", name, lineno)
+		}
+		rules = append(rules, cur)
+		cur = nil
+		return nil
+	}
+	s := bufio.NewScanner(r)
+	lineno := 0
+	for s.Scan() {
+		lineno++
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "#") {
+			if cur == nil && comment == "" {
+				comment = strings.TrimSpace(line[1:])
+			}
+			continue
+		}
+		if line == "" {
+			if err := end(lineno); err != nil {
+				return nil, err
+			}
+			comment = ""
+			continue
+		}
+		fields, err := SplitFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, lineno, err)
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%s:%d: malformed rule %q", name, lineno, line)
+		}
+		if cur == nil {
+			cur = &Rule{Name: comment}
+		}
+		obj, verb, args := fields[0], fields[1], fields[2:]
+		switch obj + " " + verb {
+		case "data matches":
+			re, err := regexp.Compile(args[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", name, lineno, err)
+			}
+			cur.Pattern = re
+		case "data set":
+			cur.Data = args[0]
+		case "arg isfile":
+			cur.IsFile = args[0]
+		case "arg iscmd":
+			cur.IsCmd = args[0]
+		case "arg iscommit":
+			cur.IsCommit = args[0]
+		case "attr add":
+			for _, a := range args {
+				kv := strings.SplitN(a, "=", 2)
+				if len(kv) != 2 {
+					return nil, fmt.Errorf("%s:%d: malformed attribute %q", name, lineno, a)
+				}
+				cur.Attrs = append(cur.Attrs, [2]string{kv[0], kv[1]})
+			}
+		case "plumb to":
+			cur.To = args[0]
+		case "plumb start":
+			cur.Start = args
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown rule %q", name, lineno, obj+" "+verb)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if err := end(lineno); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// SplitFields splits a rule line on white space. Single quotes group words
+// and a doubled quote inside quotes stands for a quote, as in rc.
+func SplitFields(line string) ([]string, error) {
+	var fields []string
+	var field []rune
+	quoted, infield := false, false
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quoted && r == '\'':
+			if i+1 < len(runes) && runes[i+1] == '\'' {
+				field = append(field, '\'')
+				i++
+				continue
+			}
+			quoted = false
+		case quoted:
+			field = append(field, r)
+		case r == '\'':
+			quoted, infield = true, true
+		case r == ' ' || r == '\t':
+			if infield {
+				fields = append(fields, string(field))
+				field, infield = nil, false
+			}
+		default:
+			field = append(field, r)
+			infield = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if infield {
+		fields = append(fields, string(field))
+	}
+	return fields, nil
+}
+
+// reject is a match whose check failed, the text the check was given is in
+// arg.
+type reject struct {
+	m     *Match
+	check string
+	arg   string
+}
+
+// apply returns the matches of r in line whose checks pass, adding the
+// others to rejects unless it is nil. Relative paths are resolved against
+// dir.
+func (r *Rule) apply(line, dir string, rejects *[]reject) []*Match {
+	rejected := func(m *Match, check, arg string) {
+		Trace("rule=%q text=%q %s=%q result=missing", r.Pattern, m.Text, check, arg)
+		if rejects != nil {
+			*rejects = append(*rejects, reject{m, check, arg})
+		}
+	}
+	var matches []*Match
+	for _, loc := range r.Pattern.FindAllStringSubmatchIndex(line, -1) {
+		m := &Match{
+			Rule:  r,
+			Text:  line[loc[0]:loc[1]],
+			Start: loc[0],
+			End:   loc[1],
+			Attrs: map[string]string{},
+		}
+		for i := 0; i < len(loc); i += 2 {
+			if loc[i] < 0 {
+				m.Subs = append(m.Subs, "")
+				continue
+			}
+			m.Subs = append(m.Subs, line[loc[i]:loc[i+1]])
+		}
+		m.Attrs["data"] = m.Text
+		m.Attrs["wdir"] = dir
+		if r.IsFile != "" {
+			file, ok := Resolve(dir, m.Expand(r.IsFile))
+			if !ok {
+				rejected(m, "isfile", m.Expand(r.IsFile))
+				continue
+			}
+			m.Attrs["file"] = file
+		}
+		if r.IsCmd != "" && !IsCommand(m.Expand(r.IsCmd)) {
+			rejected(m, "iscmd", m.Expand(r.IsCmd))
+			continue
+		}
+		if r.IsCommit != "" && !IsCommit(dir, m.Expand(r.IsCommit)) {
+			rejected(m, "iscommit", m.Expand(r.IsCommit))
+			continue
+		}
+		if r.Data != "" {
+			m.Text = m.Expand(r.Data)
+			m.Attrs["data"] = m.Text
+		}
+		for _, kv := range r.Attrs {
+			m.Attrs[kv[0]] = m.Expand(kv[1])
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// MatchLine applies every rule to line and returns the matches ordered by
+// their position. Where matches overlap the earlier rule wins, paths with
+// spaces win over all rules. Relative paths are resolved against dir.
+func MatchLine(rules []*Rule, line, dir string) []*Match {
+	matches := spacedPaths(line, dir)
+	for _, r := range rules {
+	next:
+		for _, m := range r.apply(line, dir, nil) {
+			for _, o := range matches {
+				if m.Start < o.End && o.Start < m.End {
+					Trace("rule=%q text=%q result=overlaps with=%q", r.Pattern, m.Text, o.Text)
+					continue next
+				}
+			}
+			Trace("rule=%q text=%q result=match to=%s file=%q line=%s", r.Pattern, m.Text, r.To, m.Attrs["file"], m.Attrs["line"])
+			matches = append(matches, m)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return matches
+}
+
+// Explain tells why no rule matches line: how many tokens were checked for
+// an existing path, and the one that looks the most like a path.
+func Explain(rules []*Rule, line, dir string) string {
+	var rejects []reject
+	for _, r := range rules {
+		r.apply(line, dir, &rejects)
+	}
+	seen := map[string]bool{}
+	closest := ""
+	for _, rj := range rejects {
+		arg := strings.TrimSpace(rj.arg)
+		if rj.check != "isfile" || arg == "" || seen[arg] {
+			continue
+		}
+		seen[arg] = true
+		if pathLikeness(arg) > pathLikeness(closest) {
+			closest = arg
+		}
+	}
+	if len(seen) == 0 {
+		return "no rule matches this line"
+	}
+	msg := "no existing path in the only token"
+	if len(seen) > 1 {
+		msg = fmt.Sprintf("no existing path among %d tokens", len(seen))
+	}
+	if pathLikeness(closest) > 0 {
+		msg += fmt.Sprintf("; closest candidate %s not found", closest)
+	}
+	return msg
+}
+
+// pathLikeness scores how much s looks like a path, 0 for not at all.
+func pathLikeness(s string) int {
+	if !strings.ContainsAny(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") {
+		return 0
+	}
+	n := 2 * strings.Count(s, "/")
+	if ext := filepath.Ext(s); len(ext) > 1 && len(ext) < len(s) {
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return 4*n + len(s)
+}
+
+var varRe = regexp.MustCompile(`\$([0-9]|[a-zA-Z_][a-zA-Z0-9_]*)`)
+
+// Expand replaces $0 to $9 with the submatches of the pattern and $name with
+// the attribute name, falling back to the environment.
+func (m *Match) Expand(s string) string {
+	return varRe.ReplaceAllStringFunc(s, func(v string) string {
+		name := v[1:]
+		if n, err := strconv.Atoi(name); err == nil {
+			if n < len(m.Subs) {
+				return m.Subs[n]
+			}
+			return ""
+		}
+		if a, ok := m.Attrs[name]; ok {
+			return a
+		}
+		return os.Getenv(name)
+	})
+}
@@ -0,0 +1,225 @@
+package plumb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitFields(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+		err  bool
+	}{
+		{"data matches x", []string{"data", "matches", "x"}, false},
+		{"  attr\tadd  a=1 b=2 ", []string{"attr", "add", "a=1", "b=2"}, false},
+		{"data matches 'a b'", []string{"data", "matches", "a b"}, false},
+		{"data matches 'it''s'", []string{"data", "matches", "it's"}, false},
+		{"data matches ''", []string{"data", "matches", ""}, false},
+		{"data matches x'y z'", []string{"data", "matches", "xy z"}, false},
+		{"data matches 'open", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := SplitFields(tt.line)
+		if (err != nil) != tt.err {
+			t.Errorf("SplitFields(%q) error = %v, want error %v", tt.line, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitFields(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	src := `
+# go files
+# more about them
+data matches '([a-z]+\.go):([0-9]+)'
+arg isfile $1
+attr add line=$2 kind=go
+plumb to edit
+
+data matches 'man ([a-z]+)'
+plumb to term
+plumb start man $1
+`
+	rules, err := ParseRules("test", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want 2", len(rules))
+	}
+	r := rules[0]
+	if r.Name != "go files" || r.Pattern.String() != `([a-z]+\.go):([0-9]+)` || r.IsFile != "$1" || r.To != "edit" {
+		t.Errorf("first rule = %+v", r)
+	}
+	if want := [][2]string{{"line", "$2"}, {"kind", "go"}}; !reflect.DeepEqual(r.Attrs, want) {
+		t.Errorf("attrs = %q, want %q", r.Attrs, want)
+	}
+	r = rules[1]
+	if r.Name != "" || r.To != "term" || !reflect.DeepEqual(r.Start, []string{"man", "$1"}) {
+		t.Errorf("second rule = %+v", r)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := []struct {
+		src, err string
+	}{
+		{"plumb to", "test:1: malformed rule"},
+		{"arg isfile $0\nplumb to edit", "test:2: rule has no data matches"},
+		{"data matches x", "test:1: rule has no plumb action"},
+		{"data matches x\nplumb to term", "test:2: term port needs plumb start"},
+		{"data matches (", "test:1: error parsing regexp"},
+		{"data matches 'x", "test:1: unterminated quote"},
+		{"data frobs x", `test:1: unknown rule "data frobs"`},
+		{"data matches x\nattr add line", `test:2: malformed attribute "line"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseRules("test", strings.NewReader(tt.src))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("ParseRules(%q) error = %v, want %s", tt.src, err, tt.err)
+		}
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	if _, err := ParseRules("default", strings.NewReader(DefaultRules)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMatchLine(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "util.c"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules, err := ParseRules("default", strings.NewReader(DefaultRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	main, util := filepath.Join(dir, "main.go"), filepath.Join(dir, "util.c")
+	tests := []struct {
+		line       string
+		text, to   string
+		file       string
+		line1, col int
+	}{
+		{"see https://example.com/a?b=1.", "https://example.com/a?b=1", "web", "", 0, 0},
+		{"mail me at a.b@example.org", "a.b@example.org", "mail", "", 0, 0},
+		{"util.c:12:3: error: expected ';'", "util.c:12:3", "edit", util, 12, 3},
+		{"main.go:7", "main.go:7", "edit", main, 7, 0},
+		{"at main.go:7:2", "main.go:7:2", "edit", main, 7, 2},
+		{"\tmain.go:42 +0x1f", "main.go:42", "edit", main, 42, 0},
+		{`  File "main.go", line 9, in f`, "main.go:9", "edit", main, 9, 0},
+		{"  --> main.go:4:5", "main.go:4:5", "edit", main, 4, 5},
+		{"+++ b/main.go", "main.go", "edit", main, 0, 0},
+		{"open main.go now", "main.go", "edit", main, 0, 0},
+	}
+	for _, tt := range tests {
+		ms := MatchLine(rules, tt.line, dir)
+		if len(ms) == 0 {
+			t.Errorf("MatchLine(%q) found nothing", tt.line)
+			continue
+		}
+		m := ms[0]
+		if m.Text != tt.text || m.Rule.To != tt.to || m.Attrs["file"] != tt.file || m.Line() != tt.line1 || m.Col() != tt.col {
+			t.Errorf("MatchLine(%q) = text %q to %s file %q line %d col %d, want text %q to %s file %q line %d col %d",
+				tt.line, m.Text, m.Rule.To, m.Attrs["file"], m.Line(), m.Col(), tt.text, tt.to, tt.file, tt.line1, tt.col)
+		}
+	}
+	if ms := MatchLine(rules, "nosuchfile.go:3", dir); len(ms) != 0 {
+		t.Errorf("MatchLine of a missing file = %q, want nothing", ms[0].Text)
+	}
+	// words naming commands in $PATH aren't targets
+	for _, line := range []string{"./main.go:12:5: undefined: test", "main.go:3: make sure it builds"} {
+		if ms := MatchLine(rules, line, dir); len(ms) != 1 {
+			var got []string
+			for _, m := range ms {
+				got = append(got, m.Text)
+			}
+			t.Errorf("MatchLine(%q) = %q, want only the file", line, got)
+		}
+	}
+}
+
+func TestMatchLineOrder(t *testing.T) {
+	rules, err := ParseRules("test", strings.NewReader(`
+data matches '[a-z]+'
+plumb to web
+
+data matches '[a-z]+[0-9]'
+plumb to mail
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := MatchLine(rules, "abc1 de", "")
+	var got []string
+	for _, m := range ms {
+		got = append(got, m.Text+" "+m.Rule.To)
+	}
+	// the earlier rule wins where matches overlap
+	if want := []string{"abc web", "de web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchLine = %q, want %q", got, want)
+	}
+}
+
+func TestMatchTarget(t *testing.T) {
+	dir := t.TempDir()
+	name := "my notes.txt"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := ParseRules("default", strings.NewReader(DefaultRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := MatchTarget(rules, name, dir)
+	if m == nil || m.Text != name || m.File() != filepath.Join(dir, name) {
+		t.Errorf("MatchTarget(%q) = %+v, want the whole file", name, m)
+	}
+	m = MatchTarget(rules, "https://example.com", dir)
+	if m == nil || m.Rule.To != "web" {
+		t.Errorf("MatchTarget of a url = %+v, want the web port", m)
+	}
+	if m := MatchTarget(rules, "nosuchfile.txt", dir); m != nil {
+		t.Errorf("MatchTarget of a missing file = %+v, want nil", m)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	t.Setenv("PLUMB_TEST", "env")
+	m := &Match{
+		Subs:  []string{"a.go:3", "a.go", "3"},
+		Attrs: map[string]string{"line": "3", "PLUMB_TEST": "attr"},
+	}
+	tests := []struct {
+		s, want string
+	}{
+		{"$1", "a.go"},
+		{"$1:$2", "a.go:3"},
+		{"$0", "a.go:3"},
+		{"$9", ""},
+		{"+$line", "+3"},
+		{"$PLUMB_TEST", "attr"},
+		{"$HOME_OF_NOTHING_PLUMB", ""},
+		{"no vars", "no vars"},
+	}
+	for _, tt := range tests {
+		if got := m.Expand(tt.s); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+	delete(m.Attrs, "PLUMB_TEST")
+	if got := m.Expand("$PLUMB_TEST"); got != "env" {
+		t.Errorf("Expand falls back to %q, want env", got)
+	}
+}